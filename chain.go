@@ -2,14 +2,13 @@ package eagle
 
 import "net/http"
 
+// ChainMiddleware returns a Middleware applying mw in order, the first being the outermost.
+// The chain is built once when the returned Middleware is applied, not on every request.
 func ChainMiddleware(mw ...Middleware) Middleware {
 	return func(final http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			last := final
-			for i := len(mw) - 1; i >= 0; i-- {
-				last = mw[i](last)
-			}
-			last(w, r)
+		for i := len(mw) - 1; i >= 0; i-- {
+			final = mw[i](final)
 		}
+		return final
 	}
 }
@@ -0,0 +1,169 @@
+package eagle
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// checkConflicts returns an error when pattern duplicates a registered pattern,
+// or overlaps one when overlap detection is enabled.
+func (mux *Mux) checkConflicts(pattern string) error {
+	key := matchKey(pattern)
+	for _, ri := range mux.table().routes {
+		if matchKey(ri.pattern) == key {
+			return fmt.Errorf("pattern %s duplicates the registered pattern %s", pattern, ri.pattern)
+		}
+
+		if mux.detectOverlaps && patternsOverlap(pattern, ri.pattern) {
+			return fmt.Errorf("pattern %s overlaps the registered pattern %s", pattern, ri.pattern)
+		}
+	}
+	return nil
+}
+
+// matchKey returns pattern without the names of its parameters, so that patterns matching
+// exactly the same paths have the same key.
+func matchKey(pattern string) string {
+	var b strings.Builder
+	rest := pattern
+	for len(rest) > 0 {
+		typ, seg, _, expr, err := nextSegment(rest)
+		if err != nil {
+			return pattern
+		}
+		rest = rest[len(seg):]
+
+		switch typ {
+		case ntStatic:
+			b.WriteString(seg)
+		case ntParam:
+			fmt.Fprintf(&b, "{%s}", paramExpr(expr))
+		case ntCatchAll:
+			b.WriteString("*")
+		}
+	}
+	return b.String()
+}
+
+// splitSegments splits pattern by `/` outside of path parameters.
+func splitSegments(pattern string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				segments = append(segments, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, pattern[start:])
+}
+
+// patternsOverlap reports whether a path could match both a and b.
+// Two regular expressions are considered overlapping when a sample string of one matches the other,
+// so overlaps that no sample reveals are not reported.
+// Patterns with a parameter spanning segments are compared as a whole instead of segment by segment.
+func patternsOverlap(a, b string) bool {
+	if spansSegment(a) || spansSegment(b) {
+		return segmentsOverlap(a, b)
+	}
+
+	sa, sb := splitSegments(a), splitSegments(b)
+	for i := 0; ; i++ {
+		if (i < len(sa) && strings.HasPrefix(sa[i], "*")) || (i < len(sb) && strings.HasPrefix(sb[i], "*")) {
+			return true
+		}
+
+		if i >= len(sa) || i >= len(sb) {
+			return len(sa) == len(sb)
+		}
+
+		if !segmentsOverlap(sa[i], sb[i]) {
+			return false
+		}
+	}
+}
+
+func segmentsOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	ra, sa, err := segmentRegexp(a)
+	if err != nil {
+		return true
+	}
+	rb, sb, err := segmentRegexp(b)
+	if err != nil {
+		return true
+	}
+
+	return ra.MatchString(sb) || rb.MatchString(sa)
+}
+
+// segmentRegexp returns the regular expression matching segment, or a whole pattern, and a sample string it matches.
+func segmentRegexp(segment string) (*regexp.Regexp, string, error) {
+	p, _, err := genMatchPattern(segment)
+	if err != nil {
+		return nil, "", err
+	}
+
+	re, err := syntax.Parse(p, syntax.Perl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b strings.Builder
+	writeSample(&b, re.Simplify())
+
+	rex, err := regexp.Compile(p)
+	return rex, b.String(), err
+}
+
+// writeSample writes a short string matched by re to b.
+func writeSample(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(sampleRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune('a')
+	case syntax.OpCapture, syntax.OpPlus:
+		writeSample(b, re.Sub[0])
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			writeSample(b, re.Sub[0])
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeSample(b, sub)
+		}
+	case syntax.OpAlternate:
+		writeSample(b, re.Sub[0])
+	}
+}
+
+// sampleRune returns a rune of the character class ranges, preferring readable ones.
+func sampleRune(ranges []rune) rune {
+	for _, r := range []rune{'a', '0', 'A', '-', '_'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= r && r <= ranges[i+1] {
+				return r
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return 'a'
+	}
+	return ranges[0]
+}
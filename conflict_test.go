@@ -0,0 +1,166 @@
+package eagle
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMuxDuplicateRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  bool
+	}{
+		{
+			name:     "Same pattern is a duplicate",
+			patterns: []string{"/users/{id:int}", "/users/{id:int}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Pattern differing only in a trailing slash is a duplicate",
+			patterns: []string{"/users", "/users/"},
+			wantErr:  true,
+		},
+		{
+			name:     "Pattern differing only in parameter names is a duplicate",
+			patterns: []string{"/users/{id:int}", "/users/{userID:[0-9]+}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Overlapping patterns are not reported without overlap detection",
+			patterns: []string{"/users/{id:int}", "/users/{name}"},
+			wantErr:  false,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			var err error
+			for _, p := range td.patterns {
+				if err = router.SetResource(p, &namedResource{}); err != nil {
+					break
+				}
+			}
+
+			if (err != nil) != td.wantErr {
+				t.Errorf("SetResource failed err: %s", err)
+			}
+		})
+	}
+}
+
+func TestMuxDuplicateHandleFunc(t *testing.T) {
+	router := NewRouter()
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	if err := router.HandleFunc(http.MethodGet, "/users", h); err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	if err := router.HandleFunc(http.MethodHead, "/users", h); err != nil {
+		t.Errorf("HandleFunc must override the default HEAD handler err: %s", err)
+	}
+
+	if err := router.HandleFunc(http.MethodGet, "/users", h); err == nil {
+		t.Error("HandleFunc must return an error for a method already registered")
+	}
+
+	if err := router.Handle("/users", http.HandlerFunc(h)); err == nil {
+		t.Error("Handle must return an error for a pattern already registered")
+	}
+}
+
+func TestMuxOverlapDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  bool
+	}{
+		{
+			name:     "Static and parameter matching it overlap",
+			patterns: []string{"/users/me", "/users/{id:[a-z]+}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Static and parameter not matching it do not overlap",
+			patterns: []string{"/users/me", "/users/{id:int}"},
+			wantErr:  false,
+		},
+		{
+			name:     "Parameters matching the same values overlap",
+			patterns: []string{"/users/{id:int}", "/users/{name:[0-9a-z]+}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Parameters matching different values do not overlap",
+			patterns: []string{"/users/{id:int}", "/users/{name:[a-z]+}"},
+			wantErr:  false,
+		},
+		{
+			name:     "Parameter without regular expression overlaps any segment",
+			patterns: []string{"/users/{id:int}/posts", "/users/{name}/posts"},
+			wantErr:  true,
+		},
+		{
+			name:     "Catch-all overlaps deeper patterns",
+			patterns: []string{"/files/*path", "/files/{dir}/raw"},
+			wantErr:  true,
+		},
+		{
+			name:     "Catch-all overlaps the path without it",
+			patterns: []string{"/files/*path", "/files"},
+			wantErr:  true,
+		},
+		{
+			name:     "Patterns of different length do not overlap",
+			patterns: []string{"/users/{id}", "/users/{id}/posts"},
+			wantErr:  false,
+		},
+		{
+			name:     "Slash in a regular expression is not a separator",
+			patterns: []string{"/files/{name:[^/]+}", "/files/{id:int}/raw"},
+			wantErr:  false,
+		},
+		{
+			name:     "Parameter spanning segments overlaps deeper static pattern",
+			patterns: []string{"/files/{p:.+}", "/files/a/b"},
+			wantErr:  true,
+		},
+		{
+			name:     "Static pattern overlaps parameter spanning segments registered after it",
+			patterns: []string{"/files/a/b", "/files/{p:.+}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Parameter spanning segments overlaps deeper parameter",
+			patterns: []string{"/files/{p:[a-z/]+}", "/files/{dir}/raw"},
+			wantErr:  true,
+		},
+		{
+			name:     "Parameter spanning its literal overlaps parameter of the segment",
+			patterns: []string{"/r/{n:[a-z.]+}.json", "/r/{name}"},
+			wantErr:  true,
+		},
+		{
+			name:     "Parameter spanning segments does not overlap another prefix",
+			patterns: []string{"/files/{p:.+}", "/users/a/b"},
+			wantErr:  false,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(WithOverlapDetection())
+			var err error
+			for _, p := range td.patterns {
+				if err = router.SetResource(p, &namedResource{}); err != nil {
+					break
+				}
+			}
+
+			if (err != nil) != td.wantErr {
+				t.Errorf("SetResource failed err: %v", err)
+			}
+		})
+	}
+}
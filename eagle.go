@@ -36,35 +36,98 @@ import (
 	"net/http"
 )
 
-type Resource interface {
+// Resource is a value that implements one or more of Getter, Poster, Putter, Deleter,
+// Patcher, Optioner, Header and Tracer, or of their counterparts returning an error such as ErrorGetter.
+// The Mux only dispatches the methods a Resource implements and answers others with 405 Method Not Allowed.
+type Resource interface{}
+
+type Getter interface {
 	Get(w http.ResponseWriter, r *http.Request)
+}
+
+type Poster interface {
 	Post(w http.ResponseWriter, r *http.Request)
+}
+
+type Putter interface {
 	Put(w http.ResponseWriter, r *http.Request)
+}
+
+type Deleter interface {
 	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type Patcher interface {
 	Patch(w http.ResponseWriter, r *http.Request)
+}
+
+type Optioner interface {
 	Options(w http.ResponseWriter, r *http.Request)
+}
+
+// Header handles HEAD requests. It is unrelated to http.Header.
+type Header interface {
 	Head(w http.ResponseWriter, r *http.Request)
+}
+
+type Tracer interface {
 	Trace(w http.ResponseWriter, r *http.Request)
 }
 
-type ResourceImpl struct{}
+// ErrorGetter is a Getter returning an error, which is handled by the error handler of the Mux.
+// The same applies to the other interfaces with the Error prefix.
+type ErrorGetter interface {
+	Get(w http.ResponseWriter, r *http.Request) error
+}
 
-func (resource *ResourceImpl) Get(w http.ResponseWriter, r *http.Request)     {}
-func (resource *ResourceImpl) Post(w http.ResponseWriter, r *http.Request)    {}
-func (resource *ResourceImpl) Put(w http.ResponseWriter, r *http.Request)     {}
-func (resource *ResourceImpl) Delete(w http.ResponseWriter, r *http.Request)  {}
-func (resource *ResourceImpl) Patch(w http.ResponseWriter, r *http.Request)   {}
-func (resource *ResourceImpl) Options(w http.ResponseWriter, r *http.Request) {}
-func (resource *ResourceImpl) Head(w http.ResponseWriter, r *http.Request)    {}
-func (resource *ResourceImpl) Trace(w http.ResponseWriter, r *http.Request)   {}
+type ErrorPoster interface {
+	Post(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorPutter interface {
+	Put(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorDeleter interface {
+	Delete(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorPatcher interface {
+	Patch(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorOptioner interface {
+	Options(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorHeader interface {
+	Head(w http.ResponseWriter, r *http.Request) error
+}
+
+type ErrorTracer interface {
+	Trace(w http.ResponseWriter, r *http.Request) error
+}
+
+// ResourceImpl is kept for compatibility with resources that embed it.
+// It implements no method, so only the methods defined on the embedding type are served.
+type ResourceImpl struct{}
 
 type Router interface {
 	http.Handler
-	SetResource(pattern string, resource Resource) error
+	SetResource(pattern string, resource Resource, opts ...ResourceOption) error
 	Use(middleware Middleware)
+	Group(prefix string, mw ...Middleware) Router
+	Mount(prefix string, sub *Mux) error
+	Handle(pattern string, h http.Handler) error
+	HandleFunc(method, pattern string, fn http.HandlerFunc) error
 }
 
-func NewRouter() *Mux {
-	m := make(map[string]*resourceInfo)
-	return &Mux{resources: m}
+func NewRouter(opts ...MuxOption) *Mux {
+	mux := &Mux{}
+	mux.current.Store(&routingTable{tree: &node{}})
+	for _, opt := range opts {
+		opt(mux)
+	}
+	mux.compose()
+	return mux
 }
@@ -0,0 +1,115 @@
+package eagle
+
+import (
+	"net/http"
+)
+
+// ErrorHandlerFunc handles an error returned by a resource, e.g. by ErrorGetter.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// HTTPError is an error carrying the response to send for it.
+// The default error handler renders it as JSON with Status, Code and Message; Err is not rendered.
+type HTTPError struct {
+	// Status is the status code of the response. 0 means 500 Internal Server Error.
+	Status int
+
+	// Code identifies the error for clients, e.g. "user_not_found".
+	Code string
+
+	// Message describes the error for clients.
+	Message string
+
+	// Err is the cause of the error. It is not sent to clients.
+	Err error
+}
+
+// NewHTTPError returns an HTTPError. An empty message is replaced by the status text of status.
+func NewHTTPError(status int, code, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the error that caused e.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// asHTTPError returns the first HTTPError in the chain of errors wrapped by err.
+func asHTTPError(err error) (*HTTPError, bool) {
+	for err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			return he, true
+		}
+		err = unwrapError(err)
+	}
+	return nil, false
+}
+
+// unwrapError returns the error wrapped by err, or nil.
+func unwrapError(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// ErrorHandler sets the handler for the errors returned by resources.
+// The default handler renders an HTTPError as JSON with its status, a Problem by RenderProblem,
+// and responds to other errors with 500 Internal Server Error.
+// A resource must not write the response before returning an error.
+func (mux *Mux) ErrorHandler(h ErrorHandlerFunc) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.errorHandler = h
+	mux.compose()
+}
+
+// serveError passes err to the error handler of the routingTable serving requests.
+func (mux *Mux) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	mux.table().errorHandler(w, r, err)
+}
+
+// handleErrors returns a handler passing the error returned by h to onError.
+func handleErrors(h func(http.ResponseWriter, *http.Request) error, onError ErrorHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			onError(w, r, err)
+		}
+	}
+}
+
+type errorResponse struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if p, ok := asProblem(err); ok {
+		RenderProblem(w, p)
+		return
+	}
+
+	he, ok := asHTTPError(err)
+	if !ok {
+		he = NewHTTPError(http.StatusInternalServerError, "", "")
+	}
+
+	status := he.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	message := he.Message
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	RenderJSON(w, status, &errorResponse{Code: he.Code, Message: message})
+}
@@ -0,0 +1,161 @@
+package eagle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type errorResource struct {
+	err error
+}
+
+func (er *errorResource) Get(w http.ResponseWriter, r *http.Request) error {
+	if er.err != nil {
+		return er.err
+	}
+	w.Write([]byte("ok"))
+	return nil
+}
+
+func (er *errorResource) Post(w http.ResponseWriter, r *http.Request) error {
+	return er.err
+}
+
+type wrappedError struct {
+	err error
+}
+
+func (e *wrappedError) Error() string {
+	return "wrapped: " + e.err.Error()
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+func TestMuxErrorResource(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		method string
+		code   int
+		body   string
+	}{
+		{
+			name:   "Nil error",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "ok",
+		},
+		{
+			name:   "HTTPError",
+			err:    NewHTTPError(http.StatusNotFound, "thing_not_found", "thing is not found"),
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   `{"code":"thing_not_found","message":"thing is not found"}` + "\n",
+		},
+		{
+			name:   "HTTPError without message",
+			err:    &HTTPError{Status: http.StatusConflict},
+			method: http.MethodPost,
+			code:   http.StatusConflict,
+			body:   `{"message":"Conflict"}` + "\n",
+		},
+		{
+			name:   "Wrapped HTTPError",
+			err:    &wrappedError{err: &HTTPError{Status: http.StatusBadRequest, Code: "invalid", Message: "invalid thing", Err: errors.New("cause")}},
+			method: http.MethodGet,
+			code:   http.StatusBadRequest,
+			body:   `{"code":"invalid","message":"invalid thing"}` + "\n",
+		},
+		{
+			name:   "Other error",
+			err:    errors.New("database is down"),
+			method: http.MethodGet,
+			code:   http.StatusInternalServerError,
+			body:   `{"message":"Internal Server Error"}` + "\n",
+		},
+		{
+			name:   "Default HEAD handler",
+			err:    NewHTTPError(http.StatusNotFound, "", ""),
+			method: http.MethodHead,
+			code:   http.StatusNotFound,
+			body:   `{"message":"Not Found"}` + "\n",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			if err := router.SetResource("/things", &errorResource{err: td.err}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, "/things", nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+		})
+	}
+}
+
+func TestMuxErrorHandler(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+	if err := router.SetResource("/things", &errorResource{err: errors.New("failed")}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	router.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(err.Error()))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusTeapot)
+	}
+
+	if w.Body.String() != "failed" {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), "failed")
+	}
+
+	if got := w.Header().Get("X-Middleware"); got != "global" {
+		t.Errorf("middleware result: %s, expected: %s", got, "global")
+	}
+}
+
+func TestHTTPErrorError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *HTTPError
+		want string
+	}{
+		{
+			name: "Without cause",
+			err:  NewHTTPError(http.StatusNotFound, "", ""),
+			want: "Not Found",
+		},
+		{
+			name: "With cause",
+			err:  &HTTPError{Status: http.StatusBadRequest, Message: "invalid id", Err: errors.New("not a number")},
+			want: "invalid id: not a number",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			if got := td.err.Error(); got != td.want {
+				t.Errorf("Error result: %s, expected: %s", got, td.want)
+			}
+		})
+	}
+}
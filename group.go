@@ -0,0 +1,78 @@
+package eagle
+
+import (
+	"net/http"
+	"strings"
+)
+
+var _ Router = (*group)(nil)
+
+// group is a Router that registers resources on a Mux under a shared prefix and middleware.
+type group struct {
+	mux         *Mux
+	parent      *group
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group returns a Router whose resources are registered under prefix.
+// The middleware of the group runs after the middleware of the Mux and before the middleware of each resource.
+func (mux *Mux) Group(prefix string, mw ...Middleware) Router {
+	return &group{
+		mux:         mux,
+		prefix:      strings.TrimRight(prefix, "/"),
+		middlewares: mw,
+	}
+}
+
+func (g *group) Group(prefix string, mw ...Middleware) Router {
+	return &group{
+		mux:         g.mux,
+		parent:      g,
+		prefix:      g.prefix + strings.TrimRight(prefix, "/"),
+		middlewares: mw,
+	}
+}
+
+// Use adds middleware to the group. The added middleware applies to all resources of the group.
+func (g *group) Use(m Middleware) {
+	g.mux.mu.Lock()
+	defer g.mux.mu.Unlock()
+	g.middlewares = append(g.middlewares, m)
+	g.mux.compose()
+}
+
+func (g *group) SetResource(pattern string, resource Resource, opts ...ResourceOption) error {
+	opts = append([]ResourceOption{withGroup(g)}, opts...)
+	return g.mux.SetResource(g.prefix+pattern, resource, opts...)
+}
+
+func (g *group) Handle(pattern string, h http.Handler) error {
+	return g.mux.setHandler(g.prefix+pattern, h, g)
+}
+
+func (g *group) HandleFunc(method, pattern string, fn http.HandlerFunc) error {
+	return g.mux.setHandlerFunc(method, g.prefix+pattern, fn, g)
+}
+
+func (g *group) Mount(prefix string, sub *Mux) error {
+	return g.mux.mount(g.prefix+prefix, sub, g)
+}
+
+func (g *group) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// chain returns the middleware of g and its ancestors, outermost first.
+func (g *group) chain() []Middleware {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chain(), g.middlewares...)
+}
+
+func withGroup(g *group) ResourceOption {
+	return func(ri *resourceInfo) {
+		ri.group = g
+	}
+}
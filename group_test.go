@@ -0,0 +1,147 @@
+package eagle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+
+	v1 := router.Group("/v1/")
+	admin := v1.Group("/admin", recordMiddleware("admin"))
+	public := v1.Group("/public", recordMiddleware("public"))
+	admin.Use(recordMiddleware("auth"))
+
+	if err := admin.SetResource("/users", &namedResource{name: "admin users"}, WithMiddleware(recordMiddleware("resource"))); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := public.SetResource("/users", &namedResource{name: "public users"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		code        int
+		body        string
+		middlewares []string
+	}{
+		{
+			name:        "Resource of a group is registered under its prefix",
+			path:        "/v1/admin/users",
+			code:        http.StatusOK,
+			body:        "admin users",
+			middlewares: []string{"global", "admin", "auth", "resource"},
+		},
+		{
+			name:        "Middleware of a group does not apply to other groups",
+			path:        "/v1/public/users",
+			code:        http.StatusOK,
+			body:        "public users",
+			middlewares: []string{"global", "public"},
+		},
+		{
+			name:        "Resource of a group is not registered without its prefix",
+			path:        "/users",
+			code:        http.StatusNotFound,
+			middlewares: []string{"global"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, td.middlewares) {
+				t.Errorf("middleware result: %v, expected: %v", got, td.middlewares)
+			}
+		})
+	}
+}
+
+func TestMount(t *testing.T) {
+	sub := NewRouter()
+	sub.Use(recordMiddleware("sub"))
+	if err := sub.SetResource("/", &namedResource{name: "index"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := sub.SetResource("/users/{id:[0-9]+}", &namedResource{name: "user"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+	if err := router.Mount("/api", sub); err != nil {
+		t.Fatalf("Mount failed err: %s", err)
+	}
+	admin := router.Group("/admin", recordMiddleware("admin"))
+	if err := admin.Mount("/api", sub); err != nil {
+		t.Fatalf("Mount failed err: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		code        int
+		body        string
+		middlewares []string
+	}{
+		{
+			name:        "Mounted Mux receives the path without prefix",
+			path:        "/api/users/1",
+			code:        http.StatusOK,
+			body:        "user",
+			middlewares: []string{"global", "sub"},
+		},
+		{
+			name:        "Prefix itself is passed to the mounted Mux as root",
+			path:        "/api",
+			code:        http.StatusOK,
+			body:        "index",
+			middlewares: []string{"global", "sub"},
+		},
+		{
+			name:        "Mount under a group applies the middleware of the group",
+			path:        "/admin/api/users/1",
+			code:        http.StatusOK,
+			body:        "user",
+			middlewares: []string{"global", "admin", "sub"},
+		},
+		{
+			name:        "Path unknown to the mounted Mux is not found",
+			path:        "/api/posts",
+			code:        http.StatusNotFound,
+			middlewares: []string{"global", "sub"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, td.middlewares) {
+				t.Errorf("middleware result: %v, expected: %v", got, td.middlewares)
+			}
+		})
+	}
+}
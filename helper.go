@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -18,15 +19,96 @@ var (
 	}
 )
 
+var uuidPattern = regexp.MustCompile("^" + paramTypes["uuid"] + "$")
+
 func PathParam(r *http.Request, k string) string {
-	if v := r.Context().Value(pathParamKey(k)); v != nil {
-		return v.(string)
+	v, _ := lookupPathParam(r, k)
+	return v
+}
+
+func lookupPathParam(r *http.Request, k string) (string, error) {
+	if ps := pathParamsFromContext(r.Context()); ps != nil {
+		if v, ok := ps.get(k); ok {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("path parameter does not exist key: %s", k)
+}
+
+// PathParamInt returns the path parameter k as int.
+func PathParamInt(r *http.Request, k string) (int, error) {
+	v, err := lookupPathParam(r, k)
+	if err != nil {
+		return 0, err
 	}
 
-	return ""
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("path parameter %s is not an int: %s", k, err)
+	}
+	return n, nil
+}
+
+// PathParamInt64 returns the path parameter k as int64.
+func PathParamInt64(r *http.Request, k string) (int64, error) {
+	v, err := lookupPathParam(r, k)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("path parameter %s is not an int64: %s", k, err)
+	}
+	return n, nil
+}
+
+// PathParamUUID returns the path parameter k after checking it is a UUID in its hyphenated form.
+func PathParamUUID(r *http.Request, k string) (string, error) {
+	v, err := lookupPathParam(r, k)
+	if err != nil {
+		return "", err
+	}
+
+	if !uuidPattern.MatchString(v) {
+		return "", fmt.Errorf("path parameter %s is not a uuid: %s", k, v)
+	}
+	return v, nil
+}
+
+// BindPath sets the path parameters to the fields of the struct v points to by their `path` tag.
+//
+//	type params struct {
+//		ID int `path:"id"`
+//	}
+func BindPath(r *http.Request, v interface{}) error {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return errors.New("must be a pointer to a struct")
+	}
+
+	data := make(map[string][]string)
+	for i := 0; i < typ.Elem().NumField(); i++ {
+		k := typ.Elem().Field(i).Tag.Get("path")
+		if k == "" {
+			continue
+		}
+
+		if v, err := lookupPathParam(r, k); err == nil {
+			data[k] = []string{v}
+		}
+	}
+
+	return bindData(data, v, "path")
 }
 
 func bindFormData(formData map[string][]string, v interface{}) error {
+	return bindData(formData, v, "form")
+}
+
+// bindData sets the values of data to the fields of the struct v points to by the key of their tag.
+func bindData(data map[string][]string, v interface{}, tag string) error {
 	typ := reflect.TypeOf(v).Elem()
 	val := reflect.ValueOf(v).Elem()
 
@@ -36,18 +118,18 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
-		tagValue := f.Tag.Get("form")
+		tagValue := f.Tag.Get(tag)
 		if tagValue == "" {
 			continue
 		}
 
-		formValue := formData[tagValue]
+		formValue := data[tagValue]
 		if len(formValue) == 0 {
 			continue
 		}
 
 		if formValue[0] == "" {
-			return fmt.Errorf("form value does not exist key: %s", tagValue)
+			return fmt.Errorf("%s value does not exist key: %s", tag, tagValue)
 		}
 
 		field := val.FieldByName(f.Name)
@@ -73,7 +155,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 				isOverflow = reflect.Zero(field.Type()).OverflowInt(int64(n))
 			}
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -91,7 +173,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -109,7 +191,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 				isOverflow = reflect.Zero(field.Type()).OverflowInt(n)
 			}
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -127,7 +209,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 				isOverflow = reflect.Zero(field.Type()).OverflowInt(n)
 			}
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -146,7 +228,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -164,7 +246,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -183,7 +265,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -202,7 +284,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -221,7 +303,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -240,7 +322,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -252,7 +334,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 		case reflect.Uintptr:
 			n, err := strconv.ParseUint(fv, 10, 64)
 			if err != nil || reflect.Zero(field.Type()).OverflowUint(n) {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 			field.Set(reflect.ValueOf(n).Convert(field.Type()))
 		case reflect.Float32:
@@ -265,7 +347,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 			}
 
 			if err != nil || isOverflow {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -277,7 +359,7 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 		case reflect.Float64:
 			n, err := strconv.ParseFloat(fv, 64)
 			if err != nil || reflect.Zero(field.Type()).OverflowFloat(n) {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 
 			if isPtr {
@@ -295,17 +377,22 @@ func bindFormData(formData map[string][]string, v interface{}) error {
 		case reflect.Bool:
 			b, err := strconv.ParseBool(fv)
 			if err != nil {
-				return fmt.Errorf("bind form data failed: %s", err)
+				return fmt.Errorf("bind %s data failed: %s", tag, err)
 			}
 			field.Set(reflect.ValueOf(b).Convert(field.Type()))
 		default:
-			fmt.Println(field.Type().Kind())
+			return fmt.Errorf("bind %s data failed: unsupported type %s of field %s", tag, field.Type(), f.Name)
 		}
 	}
 
 	return nil
 }
 
+// ErrUnsupportedMediaType is returned by Bind when the Content-Type of the request is not supported.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Bind decodes the body of r into v according to its Content-Type.
+// The error of the decoder is returned as it is, and ErrUnsupportedMediaType when the Content-Type is not supported.
 func Bind(r *http.Request, v interface{}) error {
 	contentType := r.Header.Get("Content-Type")
 
@@ -323,7 +410,29 @@ func Bind(r *http.Request, v interface{}) error {
 		}
 		return bindFormData(r.Form, v)
 	default:
-		return errors.New("unsupported media type")
+		return ErrUnsupportedMediaType
+	}
+}
+
+// BindHTTP is Bind returning an *HTTPError, so the error can be returned from an ErrorPoster as it is.
+// The status is 415 Unsupported Media Type for ErrUnsupportedMediaType and 400 Bad Request otherwise.
+// The error returned by Bind is kept in Err.
+func BindHTTP(r *http.Request, v interface{}) error {
+	err := Bind(r, v)
+	switch err {
+	case nil:
+		return nil
+	case ErrUnsupportedMediaType:
+		he := NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", "unsupported media type")
+		he.Err = err
+		return he
+	default:
+		return &HTTPError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_request_body",
+			Message: "invalid request body",
+			Err:     err,
+		}
 	}
 }
 
@@ -1,6 +1,8 @@
 package eagle
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -225,3 +227,136 @@ func TestBindFormData(t *testing.T) {
 		})
 	}
 }
+
+func servePathParams(t *testing.T, pattern, path string, h http.HandlerFunc) {
+	called := false
+	router := NewRouter()
+	err := router.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		h(w, r)
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	if !called {
+		t.Fatalf("handler is not called path: %s", path)
+	}
+}
+
+func TestPathParamTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "int can be obtained",
+			path: "/users/10",
+			want: 10,
+		},
+		{
+			name:    "Error because it is not an int",
+			path:    "/users/ten",
+			wantErr: true,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			servePathParams(t, "/users/{id}", td.path, func(w http.ResponseWriter, r *http.Request) {
+				n, err := PathParamInt(r, "id")
+				if (err != nil) != td.wantErr {
+					t.Errorf("PathParamInt failed err: %s", err)
+				}
+				if n != td.want {
+					t.Errorf("PathParamInt failed result: %d, expected: %d", n, td.want)
+				}
+
+				n64, err := PathParamInt64(r, "id")
+				if (err != nil) != td.wantErr {
+					t.Errorf("PathParamInt64 failed err: %s", err)
+				}
+				if n64 != int64(td.want) {
+					t.Errorf("PathParamInt64 failed result: %d, expected: %d", n64, td.want)
+				}
+
+				if _, err := PathParamInt(r, "missing"); err == nil {
+					t.Error("PathParamInt must return an error for a missing parameter")
+				}
+			})
+		})
+	}
+
+	servePathParams(t, "/users/{id}", "/users/0f8fad5b-d9cb-469f-a165-70867728950e", func(w http.ResponseWriter, r *http.Request) {
+		id, err := PathParamUUID(r, "id")
+		if err != nil || id != "0f8fad5b-d9cb-469f-a165-70867728950e" {
+			t.Errorf("PathParamUUID failed result: %s err: %s", id, err)
+		}
+	})
+
+	servePathParams(t, "/users/{id}", "/users/10", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := PathParamUUID(r, "id"); err == nil {
+			t.Error("PathParamUUID must return an error for a malformed uuid")
+		}
+	})
+}
+
+func TestBindPath(t *testing.T) {
+	type params struct {
+		UserID   int     `path:"userID"`
+		PostID   *uint32 `path:"postID"`
+		Slug     string  `path:"slug"`
+		Optional string  `path:"optional"`
+		Other    string
+	}
+
+	postID := uint32(3)
+	tests := []struct {
+		name    string
+		path    string
+		want    params
+		wantErr bool
+	}{
+		{
+			name: "Path parameters are bound by tag",
+			path: "/users/10/posts/3/hello-world",
+			want: params{UserID: 10, PostID: &postID, Slug: "hello-world"},
+		},
+		{
+			name:    "Error because a value can not be converted",
+			path:    "/users/ten/posts/3/hello-world",
+			want:    params{},
+			wantErr: true,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			servePathParams(t, "/users/{userID}/posts/{postID}/{slug}", td.path, func(w http.ResponseWriter, r *http.Request) {
+				var p params
+				err := BindPath(r, &p)
+				if (err != nil) != td.wantErr {
+					t.Errorf("BindPath failed err: %s", err)
+				}
+
+				if !reflect.DeepEqual(p, td.want) {
+					t.Errorf("BindPath failed result: %+v, expected: %+v", p, td.want)
+				}
+			})
+		})
+	}
+}
+
+func TestBindPathUnsupportedType(t *testing.T) {
+	var p struct {
+		ID [16]byte `path:"id"`
+	}
+	servePathParams(t, "/users/{id}", "/users/abc", func(w http.ResponseWriter, r *http.Request) {
+		if err := BindPath(r, &p); err == nil {
+			t.Error("BindPath must return an error for a field of an unsupported type")
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package eagle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// hostExpr matches one label of a host name. It is used by host parameters without a regular expression such as `{sub}`.
+const hostExpr = `[^.]+`
+
+// hostRoute is a sub-router serving the requests whose host matches re.
+type hostRoute struct {
+	pattern  string
+	re       *regexp.Regexp
+	withPort bool
+	mux      *Mux
+
+	// handler is mux with the middleware of the parent Mux applied.
+	handler http.HandlerFunc
+}
+
+// Host returns a Mux serving the requests whose host matches pattern, e.g. `{tenant:[a-z]+}.example.com`.
+// The host is matched case-insensitively before the path, and its parameters are read by PathParam.
+// The port of the request is ignored unless pattern contains one.
+// Hosts are tried before schemes registered by Scheme, and requests matching neither are served by mux itself.
+func (mux *Mux) Host(pattern string) (*Mux, error) {
+	p, err := genHostPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host pattern %s: %s", pattern, err)
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	t := *mux.table()
+	for _, hr := range t.hosts {
+		if hr.pattern == pattern {
+			return nil, fmt.Errorf("host pattern %s is already registered", pattern)
+		}
+	}
+
+	sub := mux.newSub()
+	t.hosts = append(t.hosts[:len(t.hosts):len(t.hosts)], &hostRoute{
+		pattern:  pattern,
+		re:       re,
+		withPort: strings.Contains(removeParams(pattern), ":"),
+		mux:      sub,
+		handler:  mux.wrap(sub.ServeHTTP),
+	})
+	mux.current.Store(&t)
+	return sub, nil
+}
+
+// schemeRoute is a sub-router serving the requests whose scheme is scheme.
+type schemeRoute struct {
+	scheme string
+	mux    *Mux
+
+	// handler is mux with the middleware of the parent Mux applied.
+	handler http.HandlerFunc
+}
+
+// Scheme returns a Mux serving the requests whose scheme is scheme, e.g. "https".
+// The scheme is "https" for requests received over TLS and "http" otherwise,
+// unless the request URI carries one or WithForwardedProto is set.
+// Requests matching no scheme are served by mux itself.
+func (mux *Mux) Scheme(scheme string) (*Mux, error) {
+	if !validScheme(scheme) {
+		return nil, fmt.Errorf("invalid scheme: %s", scheme)
+	}
+	scheme = strings.ToLower(scheme)
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	t := *mux.table()
+	for _, sr := range t.schemes {
+		if sr.scheme == scheme {
+			return nil, fmt.Errorf("scheme %s is already registered", scheme)
+		}
+	}
+
+	sub := mux.newSub()
+	t.schemes = append(t.schemes[:len(t.schemes):len(t.schemes)], &schemeRoute{
+		scheme:  scheme,
+		mux:     sub,
+		handler: mux.wrap(sub.ServeHTTP),
+	})
+	mux.current.Store(&t)
+	return sub, nil
+}
+
+// WithForwardedProto makes the Mux take the scheme of a request from the X-Forwarded-Proto
+// or Forwarded header. Set it only behind a proxy that overwrites these headers.
+func WithForwardedProto() MuxOption {
+	return func(mux *Mux) {
+		mux.forwardedProto = true
+	}
+}
+
+// newSub returns a Mux with the options of mux.
+func (mux *Mux) newSub() *Mux {
+	sub := NewRouter()
+	sub.detectOverlaps = mux.detectOverlaps
+	sub.trailingSlash = mux.trailingSlash
+	sub.cleanPath = mux.cleanPath
+	sub.rawPath = mux.rawPath
+	sub.problemDetails = mux.problemDetails
+	sub.forwardedProto = mux.forwardedProto
+	sub.compose()
+	return sub
+}
+
+// validScheme reports whether scheme is a scheme of RFC 3986.
+func validScheme(scheme string) bool {
+	if scheme == "" {
+		return false
+	}
+	for i := 0; i < len(scheme); i++ {
+		c := scheme[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestScheme returns the lower case scheme of r.
+func (mux *Mux) requestScheme(r *http.Request) string {
+	if mux.forwardedProto {
+		if proto := forwardedProto(r.Header); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// forwardedProto returns the protocol of the first proxy in the Forwarded or X-Forwarded-Proto header.
+func forwardedProto(h http.Header) string {
+	if f := h.Get("Forwarded"); f != "" {
+		first := strings.SplitN(f, ",", 2)[0]
+		for _, pair := range strings.Split(first, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "proto") {
+				return strings.Trim(kv[1], `"`)
+			}
+		}
+	}
+
+	if p := h.Get("X-Forwarded-Proto"); p != "" {
+		return strings.TrimSpace(strings.SplitN(p, ",", 2)[0])
+	}
+	return ""
+}
+
+// matchScheme returns the schemeRoute of schemes matching the scheme of r.
+func (mux *Mux) matchScheme(schemes []*schemeRoute, r *http.Request) *schemeRoute {
+	scheme := mux.requestScheme(r)
+	for _, sr := range schemes {
+		if sr.scheme == scheme {
+			return sr
+		}
+	}
+	return nil
+}
+
+// removeParams returns pattern without its `{}` parameters.
+func removeParams(pattern string) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case depth == 0:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// genHostPattern converts a host pattern into a case-insensitive regular expression anchored to the whole host.
+func genHostPattern(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	rest := pattern
+	for {
+		si := strings.Index(rest, "{")
+		ei := strings.Index(rest, "}")
+
+		if si != -1 && ei == -1 {
+			return "", errors.New("The host parameter's } is not set")
+		}
+
+		if (si == -1 && ei != -1) || ei < si {
+			return "", errors.New("The host parameter's { is not set")
+		}
+
+		if si == -1 && ei == -1 {
+			b.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+
+		label, value := splitParam(rest[si+1 : ei])
+		if !paramNamePattern.MatchString(label) {
+			return "", fmt.Errorf("invalid host parameter name: %s", label)
+		}
+
+		expr := hostExpr
+		if value != "" {
+			expr = paramExpr(value)
+		}
+
+		b.WriteString(regexp.QuoteMeta(rest[0:si]))
+		fmt.Fprintf(&b, "(?P<%s>%s)", label, expr)
+		rest = rest[ei+1:]
+	}
+	b.WriteString("$")
+
+	return b.String(), nil
+}
+
+// stripPort removes the port from host.
+func stripPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i == -1 || strings.IndexByte(host[i:], ']') != -1 {
+		return host
+	}
+	return host[:i]
+}
+
+// matchHost returns the hostRoute of hosts matching host and adds the host parameters to params.
+func matchHost(hosts []*hostRoute, host string, params *pathParams) *hostRoute {
+	for _, hr := range hosts {
+		h := host
+		if !hr.withPort {
+			h = stripPort(host)
+		}
+
+		match := hr.re.FindStringSubmatch(h)
+		if match == nil {
+			continue
+		}
+
+		for i, name := range hr.re.SubexpNames() {
+			if name != "" {
+				params.add(name, match[i])
+			}
+		}
+		return hr
+	}
+	return nil
+}
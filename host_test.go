@@ -0,0 +1,276 @@
+package eagle
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: "api.example.com", want: `(?i)^api\.example\.com$`},
+		{pattern: "{sub}.example.com", want: `(?i)^(?P<sub>[^.]+)\.example\.com$`},
+		{pattern: "{sub:[a-z]+}.example.com:8080", want: `(?i)^(?P<sub>[a-z]+)\.example\.com:8080$`},
+		{pattern: "{sub.example.com", wantErr: true},
+		{pattern: "sub}.example.com", wantErr: true},
+	}
+
+	for _, td := range tests {
+		t.Run(td.pattern, func(t *testing.T) {
+			got, err := genHostPattern(td.pattern)
+			if (err != nil) != td.wantErr {
+				t.Errorf("genHostPattern failed err: %s", err)
+			}
+
+			if got != td.want {
+				t.Errorf("genHostPattern result: %s, expected: %s", got, td.want)
+			}
+		})
+	}
+}
+
+func TestMuxHost(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+
+	api, err := router.Host("api.example.com")
+	if err != nil {
+		t.Fatalf("Host failed err: %s", err)
+	}
+	if err := api.SetResource("/things", &namedResource{name: "api"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tenant, err := router.Host("{tenant:[a-z]+}.example.com")
+	if err != nil {
+		t.Fatalf("Host failed err: %s", err)
+	}
+	err = tenant.HandleFunc(http.MethodGet, "/things/{id:int}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(PathParam(r, "tenant") + ":" + PathParam(r, "id")))
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	if err := router.SetResource("/things", &namedResource{name: "default"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		host string
+		path string
+		code int
+		body string
+	}{
+		{
+			name: "Static host is matched before the path",
+			host: "api.example.com",
+			path: "/things",
+			code: http.StatusOK,
+			body: "api",
+		},
+		{
+			name: "Host is matched case-insensitively and without port",
+			host: "API.example.com:8080",
+			path: "/things",
+			code: http.StatusOK,
+			body: "api",
+		},
+		{
+			name: "Host parameters can be read by PathParam",
+			host: "admin.example.com",
+			path: "/things/3",
+			code: http.StatusOK,
+			body: "admin:3",
+		},
+		{
+			name: "Path unknown to the matched host is not found",
+			host: "admin.example.com",
+			path: "/things",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "Request matching no host is served by the Mux",
+			host: "www.other.com",
+			path: "/things",
+			code: http.StatusOK,
+			body: "default",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, td.path, nil)
+			r.Host = td.host
+			router.ServeHTTP(w, r)
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, []string{"global"}) {
+				t.Errorf("middleware result: %v, expected: %v", got, []string{"global"})
+			}
+		})
+	}
+
+	if _, err := router.Host("api.example.com"); err == nil {
+		t.Error("Host must return an error for a pattern already registered")
+	}
+}
+
+func TestMuxScheme(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []MuxOption
+		tls    bool
+		target string
+		header http.Header
+		want   string
+	}{
+		{
+			name:   "Plain request",
+			target: "/things",
+			want:   "http",
+		},
+		{
+			name:   "TLS request",
+			tls:    true,
+			target: "/things",
+			want:   "https",
+		},
+		{
+			name:   "Scheme of the request URI",
+			target: "https://example.com/things",
+			want:   "https",
+		},
+		{
+			name:   "Forwarded headers are ignored by default",
+			target: "/things",
+			header: http.Header{"X-Forwarded-Proto": {"https"}},
+			want:   "http",
+		},
+		{
+			name:   "X-Forwarded-Proto",
+			opts:   []MuxOption{WithForwardedProto()},
+			target: "/things",
+			header: http.Header{"X-Forwarded-Proto": {"HTTPS, http"}},
+			want:   "https",
+		},
+		{
+			name:   "Forwarded",
+			opts:   []MuxOption{WithForwardedProto()},
+			tls:    true,
+			target: "/things",
+			header: http.Header{"Forwarded": {`for=192.0.2.60;proto="http";by=203.0.113.43, proto=https`}},
+			want:   "http",
+		},
+		{
+			name:   "Scheme without sub-router",
+			target: "ftp://example.com/things",
+			want:   "default",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(td.opts...)
+			router.Use(recordMiddleware("global"))
+			if err := router.SetResource("/things", &namedResource{name: "default"}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+			for _, scheme := range []string{"http", "HTTPS"} {
+				sub, err := router.Scheme(scheme)
+				if err != nil {
+					t.Fatalf("Scheme failed err: %s", err)
+				}
+				if err := sub.SetResource("/things", &namedResource{name: strings.ToLower(scheme)}); err != nil {
+					t.Fatalf("SetResource failed err: %s", err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, td.target, nil)
+			if !td.tls {
+				r.TLS = nil
+			} else if r.TLS == nil {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range td.header {
+				r.Header[k] = v
+			}
+			router.ServeHTTP(w, r)
+
+			if w.Body.String() != td.want {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.want)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, []string{"global"}) {
+				t.Errorf("middleware result: %v, expected: %v", got, []string{"global"})
+			}
+		})
+	}
+}
+
+func TestMuxSchemeInvalid(t *testing.T) {
+	router := NewRouter()
+	if _, err := router.Scheme("https"); err != nil {
+		t.Fatalf("Scheme failed err: %s", err)
+	}
+
+	for _, scheme := range []string{"", "1http", "ht tp", "HTTPS"} {
+		t.Run(scheme, func(t *testing.T) {
+			if _, err := router.Scheme(scheme); err == nil {
+				t.Errorf("Scheme must return an error for scheme: %q", scheme)
+			}
+		})
+	}
+}
+
+func TestMuxHostAndScheme(t *testing.T) {
+	router := NewRouter()
+	api, err := router.Host("api.example.com")
+	if err != nil {
+		t.Fatalf("Host failed err: %s", err)
+	}
+	secure, err := api.Scheme("https")
+	if err != nil {
+		t.Fatalf("Scheme failed err: %s", err)
+	}
+	if err := secure.SetResource("/things", &namedResource{name: "secure api"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := api.SetResource("/things", &namedResource{name: "api"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "https://api.example.com/things", want: "secure api"},
+		{target: "http://api.example.com/things", want: "api"},
+	}
+
+	for _, td := range tests {
+		t.Run(td.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.target, nil))
+			if w.Body.String() != td.want {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.want)
+			}
+		})
+	}
+}
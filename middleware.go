@@ -2,6 +2,7 @@ package eagle
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -58,7 +59,7 @@ func CORS(config CORSHeaders) Middleware {
 			w.Header().Add("Access-Control-Allow-Methods", am)
 			w.Header().Add("Access-Control-Allow-Origin", allowOrigin)
 			if ma > 0 {
-				w.Header().Set("Access-Control-Max-Age", string(ma))
+				w.Header().Set("Access-Control-Max-Age", strconv.FormatUint(uint64(ma), 10))
 			}
 
 			if ac {
@@ -1,30 +1,302 @@
 package eagle
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 var _ Router = (*Mux)(nil)
 
-type resourceInfoMap map[string]*resourceInfo
-
-const pathParamPrefix = "EaglePathParam:"
+// methods is the order methods are listed in the Allow header.
+var methods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodTrace,
+}
 
 type resourceInfo struct {
-	resource   Resource
-	isRegExp   bool
-	middleware Middleware
+	pattern  string
+	name     string
+	resource Resource
+	re       *regexp.Regexp
+	handlers map[string]http.HandlerFunc
+	allow    string
+
+	// funcs are the handlers registered by HandleFunc. They are kept when the resource is replaced.
+	funcs map[string]http.HandlerFunc
+
+	// defaults are the methods served by the default HEAD and OPTIONS handlers.
+	defaults map[string]bool
+
+	// handler serves every method when set.
+	handler http.HandlerFunc
+
+	// mounted is set when handler serves a Mux mounted by Mount.
+	mounted bool
+
+	middlewares       []Middleware
+	methodMiddlewares map[string][]Middleware
+	group             *group
+
+	// chains are the handlers keyed by method with all middleware applied.
+	// fallback serves the other methods. Both are rebuilt by Mux.compose.
+	chains   map[string]http.HandlerFunc
+	fallback http.HandlerFunc
+}
+
+// ResourceOption configures a resource registered by SetResource.
+type ResourceOption func(ri *resourceInfo)
+
+// WithMiddleware adds middleware that applies only to the resource.
+// It runs after the middleware added to the Mux by Use.
+func WithMiddleware(mw ...Middleware) ResourceOption {
+	return func(ri *resourceInfo) {
+		ri.middlewares = append(ri.middlewares, mw...)
+	}
+}
+
+// WithName names the resource so that its URL can be built by Mux.URL.
+func WithName(name string) ResourceOption {
+	return func(ri *resourceInfo) {
+		ri.name = name
+	}
+}
+
+// WithMethodMiddleware adds middleware that applies only to method of the resource.
+// It runs after the middleware added by WithMiddleware. Middleware for GET also applies to the default HEAD handler,
+// and middleware for HEAD and OPTIONS applies to the default handlers of those methods.
+func WithMethodMiddleware(method string, mw ...Middleware) ResourceOption {
+	return func(ri *resourceInfo) {
+		if ri.methodMiddlewares == nil {
+			ri.methodMiddlewares = make(map[string][]Middleware)
+		}
+		ri.methodMiddlewares[method] = append(ri.methodMiddlewares[method], mw...)
+	}
+}
+
+// setResource sets the handlers of resource to ri and applies the method middleware of ri to them.
+func (ri *resourceInfo) setResource(resource Resource, onError ErrorHandlerFunc) error {
+	handlers := resourceHandlers(resource, onError)
+	if len(handlers) == 0 {
+		return fmt.Errorf("resource %T does not implement any HTTP method", resource)
+	}
+	for method, fn := range ri.funcs {
+		if _, ok := handlers[method]; ok {
+			return fmt.Errorf("%s %s is already registered", method, ri.pattern)
+		}
+		handlers[method] = fn
+	}
+
+	ri.resource = resource
+	ri.handlers = handlers
+	ri.setDefaultHandlers()
+
+	for method, mw := range ri.methodMiddlewares {
+		h, ok := handlers[method]
+		if !ok {
+			return fmt.Errorf("resource %T does not implement %s", resource, method)
+		}
+		handlers[method] = ChainMiddleware(mw...)(h)
+	}
+	return nil
+}
+
+// resourceHandlers returns the handlers of the methods resource implements keyed by method.
+// The errors returned by the methods of the Error interfaces are passed to onError.
+func resourceHandlers(resource Resource, onError ErrorHandlerFunc) map[string]http.HandlerFunc {
+	handlers := make(map[string]http.HandlerFunc)
+	if r, ok := resource.(Getter); ok {
+		handlers[http.MethodGet] = r.Get
+	} else if r, ok := resource.(ErrorGetter); ok {
+		handlers[http.MethodGet] = handleErrors(r.Get, onError)
+	}
+	if r, ok := resource.(Poster); ok {
+		handlers[http.MethodPost] = r.Post
+	} else if r, ok := resource.(ErrorPoster); ok {
+		handlers[http.MethodPost] = handleErrors(r.Post, onError)
+	}
+	if r, ok := resource.(Putter); ok {
+		handlers[http.MethodPut] = r.Put
+	} else if r, ok := resource.(ErrorPutter); ok {
+		handlers[http.MethodPut] = handleErrors(r.Put, onError)
+	}
+	if r, ok := resource.(Deleter); ok {
+		handlers[http.MethodDelete] = r.Delete
+	} else if r, ok := resource.(ErrorDeleter); ok {
+		handlers[http.MethodDelete] = handleErrors(r.Delete, onError)
+	}
+	if r, ok := resource.(Patcher); ok {
+		handlers[http.MethodPatch] = r.Patch
+	} else if r, ok := resource.(ErrorPatcher); ok {
+		handlers[http.MethodPatch] = handleErrors(r.Patch, onError)
+	}
+	if r, ok := resource.(Optioner); ok {
+		handlers[http.MethodOptions] = r.Options
+	} else if r, ok := resource.(ErrorOptioner); ok {
+		handlers[http.MethodOptions] = handleErrors(r.Options, onError)
+	}
+	if r, ok := resource.(Header); ok {
+		handlers[http.MethodHead] = r.Head
+	} else if r, ok := resource.(ErrorHeader); ok {
+		handlers[http.MethodHead] = handleErrors(r.Head, onError)
+	}
+	if r, ok := resource.(Tracer); ok {
+		handlers[http.MethodTrace] = r.Trace
+	} else if r, ok := resource.(ErrorTracer); ok {
+		handlers[http.MethodTrace] = handleErrors(r.Trace, onError)
+	}
+	return handlers
+}
+
+// setDefaultHandlers answers HEAD with the GET handler and OPTIONS with the Allow header
+// unless the resource implements them, then updates the Allow header.
+func (ri *resourceInfo) setDefaultHandlers() {
+	if ri.defaults == nil {
+		ri.defaults = make(map[string]bool)
+	}
+
+	if _, ok := ri.handlers[http.MethodGet]; ok {
+		if _, ok := ri.handlers[http.MethodHead]; !ok {
+			ri.handlers[http.MethodHead] = ri.head
+			ri.defaults[http.MethodHead] = true
+		}
+	}
+
+	if _, ok := ri.handlers[http.MethodOptions]; !ok {
+		ri.handlers[http.MethodOptions] = ri.options
+		ri.defaults[http.MethodOptions] = true
+	}
+
+	ri.allow = allowHeader(ri.handlers)
+}
+
+// head answers HEAD with the GET handler. The server discards the body of the response,
+// while the headers it derives from the body such as Content-Length are kept.
+func (ri *resourceInfo) head(w http.ResponseWriter, r *http.Request) {
+	ri.handlers[http.MethodGet](w, r)
+}
+
+func (ri *resourceInfo) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", ri.allow)
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// allowHeader returns the value of the Allow header for handlers.
+func allowHeader(handlers map[string]http.HandlerFunc) string {
+	return strings.Join(allowedMethods(handlers), ", ")
+}
+
+// allowedMethods returns the methods of handlers in the order of methods,
+// followed by the other methods in alphabetical order.
+func allowedMethods(handlers map[string]http.HandlerFunc) []string {
+	allowed := make([]string, 0, len(handlers))
+	for _, m := range methods {
+		if _, ok := handlers[m]; ok {
+			allowed = append(allowed, m)
+		}
+	}
+
+	var extra []string
+	for m := range handlers {
+		if !isStandardMethod(m) {
+			extra = append(extra, m)
+		}
+	}
+	sort.Strings(extra)
+	return append(allowed, extra...)
+}
+
+func isStandardMethod(method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// validMethod reports whether method is a token without lower case letters, e.g. GET or PROPFIND.
+// Methods are case-sensitive, so a lower case method would never match a request.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) != -1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mux is a Router. Resources and middleware can be registered while it serves requests.
+// A request is served by the routes registered when it arrived.
 type Mux struct {
 	handler     http.Handler
-	resources   resourceInfoMap
 	middlewares []Middleware
+
+	notFound         http.HandlerFunc
+	methodNotAllowed http.HandlerFunc
+	errorHandler     ErrorHandlerFunc
+
+	detectOverlaps bool
+	trailingSlash  TrailingSlashPolicy
+	cleanPath      CleanPathPolicy
+	rawPath        bool
+	problemDetails bool
+	forwardedProto bool
+
+	// mu serializes registration. current holds the *routingTable serving requests,
+	// which is replaced instead of changed so that routes can be registered while serving.
+	mu      sync.Mutex
+	current atomic.Value
+}
+
+// TrailingSlashPolicy decides how a trailing slash of the request path is matched.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashLenient ignores trailing slashes of patterns and request paths, so "/things" and "/things/" are the same resource.
+	TrailingSlashLenient TrailingSlashPolicy = iota
+
+	// TrailingSlashStrict matches a trailing slash exactly as it is written in the pattern.
+	TrailingSlashStrict
+
+	// TrailingSlashRedirect matches like TrailingSlashStrict and redirects a request to the path
+	// with or without the trailing slash when only that form is registered.
+	// GET and HEAD are redirected with 301 and other methods with 308 so that the body is sent again.
+	TrailingSlashRedirect
+)
+
+// WithTrailingSlash sets the trailing slash policy of the Mux. The default is TrailingSlashLenient.
+func WithTrailingSlash(policy TrailingSlashPolicy) MuxOption {
+	return func(mux *Mux) {
+		mux.trailingSlash = policy
+	}
+}
+
+// MuxOption configures a Mux created by NewRouter.
+type MuxOption func(mux *Mux)
+
+// WithOverlapDetection makes registration fail when the pattern overlaps a registered pattern,
+// i.e. when some path could match both of them.
+func WithOverlapDetection() MuxOption {
+	return func(mux *Mux) {
+		mux.detectOverlaps = true
+	}
 }
 
 type Middleware func(next http.HandlerFunc) http.HandlerFunc
@@ -37,141 +309,530 @@ func handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusMethodNotAllowed)
 }
 
+// paramTypes are the named types usable in place of a regular expression in a path parameter.
+//   - int: `{id:int}` matches decimal digits
+//   - slug: `{slug:slug}` matches lower case letters and digits separated by single hyphens
+//   - uuid: `{id:uuid}` matches a UUID in its hyphenated form
+var paramTypes = map[string]string{
+	"int":  `[0-9]+`,
+	"slug": `[a-z0-9]+(?:-[a-z0-9]+)*`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// segmentExpr matches one path segment. It is used by parameters without a regular expression such as `{id}`.
+const segmentExpr = `[^/]+`
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// splitParam splits the inside of `{name:expr}` into the name and the expression.
+func splitParam(param string) (string, string) {
+	if i := strings.Index(param, ":"); i != -1 {
+		return param[:i], param[i+1:]
+	}
+	return param, ""
+}
+
+// paramExpr returns the regular expression of a path parameter whose expression is expr.
+func paramExpr(expr string) string {
+	if expr == "" {
+		return segmentExpr
+	}
+	if t, ok := paramTypes[expr]; ok {
+		return t
+	}
+	return expr
+}
+
+// catchAllIndex returns the index of the `*` starting the catch-all parameter of pattern, or -1.
+func catchAllIndex(pattern string) int {
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '*':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// genMatchPattern converts pattern into a regular expression anchored to the whole path.
+// A catch-all parameter such as `/files/*path` matches the rest of the path including slashes,
+// and also matches the path without it (`/files`).
 func genMatchPattern(pattern string) (string, bool, error) {
 	p := strings.TrimRight(pattern, "/")
 	isRegExp := false
 
+	rest := p
+	catchAll := ""
+	hasCatchAll := false
+	if i := catchAllIndex(p); i != -1 {
+		if i == 0 || p[i-1] != '/' {
+			return pattern, false, errors.New("catch-all parameter must follow /")
+		}
+
+		if strings.ContainsAny(p[i+1:], "/{}*") {
+			return pattern, false, errors.New("catch-all parameter must be the last segment")
+		}
+
+		hasCatchAll = true
+		catchAll = p[i+1:]
+		rest = p[:i-1]
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
 	for {
-		si := strings.Index(p, "{")
-		ei := strings.Index(p, "}")
+		si := strings.Index(rest, "{")
+		ei := strings.Index(rest, "}")
 
 		if si != -1 && ei == -1 {
 			return p, false, errors.New("The path parameter's } is not set")
 		}
 
-		if si == -1 && ei != -1 {
+		if (si == -1 && ei != -1) || ei < si {
 			return p, false, errors.New("The path parameter's { is not set")
 		}
 
 		if si == -1 && ei == -1 {
+			b.WriteString(regexp.QuoteMeta(rest))
 			break
 		}
 
-		param := p[si+1 : ei]
-
-		separatorIndex := strings.Index(param, ":")
-		if separatorIndex == -1 {
-			return pattern, false, errors.New("delimiters are not set in path parameters")
-		}
+		param := rest[si+1 : ei]
 
 		if strings.Index(param, "}") != -1 || strings.Index(param, "{") != -1 {
 			return pattern, false, errors.New("`{}` Can not be used as a regular expression pattern")
 		}
 
+		label, value := splitParam(param)
+		if !paramNamePattern.MatchString(label) {
+			return pattern, false, fmt.Errorf("invalid path parameter name: %s", label)
+		}
+
+		isRegExp = true
+
+		b.WriteString(regexp.QuoteMeta(rest[0:si]))
+		fmt.Fprintf(&b, "(?P<%s>%s)", label, paramExpr(value))
+		rest = rest[ei+1:]
+	}
+
+	if hasCatchAll {
 		isRegExp = true
+		if catchAll == "" {
+			b.WriteString("(?:/(.*))?")
+		} else {
+			fmt.Fprintf(&b, "(?:/(?P<%s>.*))?", catchAll)
+		}
+	}
+	b.WriteString("$")
+
+	return b.String(), isRegExp, nil
+}
+
+func findResourceInfoByRequestPath(tree *node, path string, params *pathParams) *resourceInfo {
+	return tree.find(path, params)
+}
+
+// NotFound sets the handler for requests that match no resource.
+// The middleware added by Use also runs for these requests.
+func (mux *Mux) NotFound(h http.HandlerFunc) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.notFound = h
+	mux.compose()
+}
+
+// MethodNotAllowed sets the handler for requests whose method the matched resource does not serve.
+// The Allow header is already set when h is called.
+func (mux *Mux) MethodNotAllowed(h http.HandlerFunc) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.methodNotAllowed = h
+	mux.compose()
+}
+
+// Middleware Add Middleware to mux. The added middleware applies to all resources
+func (mux *Mux) Use(m Middleware) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.middlewares = append(mux.middlewares, m)
+	mux.compose()
+}
+
+// SetResource registers resource under pattern.
+//
+// When several patterns match a request path, the Mux picks one deterministically.
+// The path is matched from left to right and at every position:
+//   - a static segment beats a parameterised segment, which beats a catch-all
+//   - a parameter followed by a literal (e.g. `{id:[0-9]+}.json`) beats one spanning the whole segment
+//   - otherwise the pattern registered first wins
+//
+// If the chosen branch does not match the rest of the path, the next candidate is tried.
+//
+// A path parameter is written as `{name}` to match one path segment, as `{name:type}` with one of
+// int, slug and uuid, or as `{name:regexp}` with a regular expression that does not contain `{}`.
+// A catch-all parameter `*name` at the end of pattern matches the rest of the path.
+// A parameter whose regular expression can match `/` or the literal following it, e.g. `/files/{path:.+}`,
+// takes the longest value that lets the rest of the pattern match.
+//
+// An error is returned when a pattern matching exactly the same paths is already registered.
+func (mux *Mux) SetResource(pattern string, resource Resource, opts ...ResourceOption) error {
+	ri := &resourceInfo{}
+	for _, opt := range opts {
+		opt(ri)
+	}
+	if err := ri.setResource(resource, mux.serveError); err != nil {
+		return err
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	return mux.insert(pattern, ri)
+}
 
-		label := param[0:separatorIndex]
-		value := param[separatorIndex+1:]
+// ReplaceResource serves resource instead of the resource registered under pattern.
+// The name and the middleware of the registered resource are kept, and so are the handlers registered by HandleFunc
+// under pattern; ReplaceResource fails if resource also implements one of their methods.
+// A Mux mounted by Mount is replaced by passing the prefix given to Mount.
+// Requests being served when ReplaceResource is called are completed by the registered resource.
+func (mux *Mux) ReplaceResource(pattern string, resource Resource) error {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	old := mux.findResource(pattern)
+	if old == nil {
+		return fmt.Errorf("resource does not exist pattern: %s", pattern)
+	}
 
-		p = fmt.Sprintf("%s(?P<%s>%s)%s", p[0:si], label, value, p[ei+1:])
+	ri := &resourceInfo{
+		pattern:           old.pattern,
+		name:              old.name,
+		re:                old.re,
+		funcs:             old.funcs,
+		middlewares:       old.middlewares,
+		methodMiddlewares: old.methodMiddlewares,
+		group:             old.group,
+	}
+	if err := ri.setResource(resource, mux.serveError); err != nil {
+		return err
 	}
+	return mux.replace(old, ri)
+}
 
-	return p, isRegExp, nil
+// RemoveResource stops serving the resource registered under pattern.
+// A Mux mounted by Mount is removed by passing the prefix given to Mount.
+// Requests being served when RemoveResource is called are completed by the resource.
+func (mux *Mux) RemoveResource(pattern string) error {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	old := mux.findResource(pattern)
+	if old == nil {
+		return fmt.Errorf("resource does not exist pattern: %s", pattern)
+	}
+
+	routes := make([]*resourceInfo, 0, len(mux.table().routes)-1)
+	for _, ri := range mux.table().routes {
+		if ri != old {
+			routes = append(routes, ri)
+		}
+	}
+	mux.rebuild(routes)
+	return nil
 }
 
-func findResourceInfoByRequestPath(resources resourceInfoMap, path string) (*resourceInfo, map[string]string) {
-	params := make(map[string]string)
-	for pattern, ri := range resources {
-		if ri.isRegExp {
-			re := regexp.MustCompile(pattern)
-			match := re.FindSubmatch([]byte(path))
-			if len(match) == 0 {
-				continue
+// Mount serves sub under prefix. sub receives requests with prefix removed from the path.
+// With WithRawPath, sub also receives the escaped path.
+func (mux *Mux) Mount(prefix string, sub *Mux) error {
+	return mux.mount(prefix, sub, nil)
+}
+
+func (mux *Mux) mount(prefix string, sub *Mux, g *group) error {
+	prefix = strings.TrimRight(prefix, "/")
+	h := func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		rest := pathParamsFromContext(r.Context()).rest
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		if mux.rawPath {
+			// rest is escaped, so that an encoded `/` stays a part of a path parameter.
+			if p, err := url.PathUnescape(rest); err == nil {
+				r2.URL.Path = p
+				r2.URL.RawPath = rest
 			}
-			for i, name := range re.SubexpNames() {
-				if i != 0 && name != "" {
-					params[name] = string(match[i])
-				}
+		}
+		sub.ServeHTTP(w, r2)
+	}
+
+	ri := &resourceInfo{
+		resource: sub,
+		handler:  h,
+		group:    g,
+		mounted:  true,
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	return mux.insert(prefix+"/*", ri)
+}
+
+// Handle registers h under pattern for every method.
+func (mux *Mux) Handle(pattern string, h http.Handler) error {
+	return mux.setHandler(pattern, h, nil)
+}
+
+func (mux *Mux) setHandler(pattern string, h http.Handler, g *group) error {
+	ri := &resourceInfo{
+		resource: h,
+		handler:  h.ServeHTTP,
+		group:    g,
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	return mux.insert(pattern, ri)
+}
+
+// HandleFunc registers fn under pattern for method.
+// Handlers registered under the same pattern are served as one resource.
+// method is case-sensitive and must be upper case, e.g. GET or PROPFIND.
+func (mux *Mux) HandleFunc(method, pattern string, fn http.HandlerFunc) error {
+	return mux.setHandlerFunc(method, pattern, fn, nil)
+}
+
+func (mux *Mux) setHandlerFunc(method, pattern string, fn http.HandlerFunc, g *group) error {
+	if !validMethod(method) {
+		return fmt.Errorf("invalid method: %s", method)
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if ri := mux.findRoute(pattern); ri != nil && ri.handlers != nil && ri.group == g {
+		if _, ok := ri.handlers[method]; ok && !ri.defaults[method] {
+			return fmt.Errorf("%s %s is already registered", method, ri.pattern)
+		}
+
+		// ri may be serving requests, so the merged resource is a copy.
+		merged := *ri
+		merged.handlers = map[string]http.HandlerFunc{method: fn}
+		merged.defaults = nil
+		merged.funcs = map[string]http.HandlerFunc{method: fn}
+		for m, h := range ri.funcs {
+			merged.funcs[m] = h
+		}
+		for m, h := range ri.handlers {
+			if !ri.defaults[m] {
+				merged.handlers[m] = h
 			}
-			return ri, params
-		} else {
-			if path == pattern {
-				return ri, params
+		}
+		merged.setDefaultHandlers()
+		for m, mw := range merged.methodMiddlewares {
+			if m == method || merged.defaults[m] {
+				merged.handlers[m] = ChainMiddleware(mw...)(merged.handlers[m])
 			}
 		}
+		return mux.replace(ri, &merged)
 	}
-	return nil, params
+
+	ri := &resourceInfo{
+		handlers: map[string]http.HandlerFunc{method: fn},
+		funcs:    map[string]http.HandlerFunc{method: fn},
+		group:    g,
+	}
+	ri.setDefaultHandlers()
+	return mux.insert(pattern, ri)
 }
 
-// Middleware Add Middleware to mux. The added middleware applies to all resources
-func (mux *Mux) Use(m Middleware) {
-	mux.middlewares = append(mux.middlewares, m)
+// findRoute returns the resourceInfo registered under pattern.
+func (mux *Mux) findRoute(pattern string) *resourceInfo {
+	return mux.table().findRoute(mux.normalizePattern(pattern))
 }
 
-// SetResource
-func (mux *Mux) SetResource(pattern string, resource Resource) error {
-	p, isRegExp, err := genMatchPattern(pattern)
+// findResource is findRoute that also accepts the prefix a Mux was mounted under by Mount.
+func (mux *Mux) findResource(pattern string) *resourceInfo {
+	if ri := mux.findRoute(pattern); ri != nil {
+		return ri
+	}
+	if ri := mux.findRoute(strings.TrimRight(pattern, "/") + "/*"); ri != nil && ri.mounted {
+		return ri
+	}
+	return nil
+}
+
+// normalizePattern removes the trailing slash of pattern unless the trailing slash policy keeps it.
+func (mux *Mux) normalizePattern(pattern string) string {
+	if mux.trailingSlash == TrailingSlashLenient || catchAllIndex(pattern) != -1 {
+		return strings.TrimRight(pattern, "/")
+	}
+
+	if pattern == "" {
+		return "/"
+	}
+	return pattern
+}
+
+func (mux *Mux) insert(pattern string, ri *resourceInfo) error {
+	p, _, err := genMatchPattern(pattern)
 	if err != nil {
 		return err
 	}
 
-	ri := &resourceInfo{
-		resource:   resource,
-		isRegExp:   isRegExp,
-		middleware: nil,
+	ri.pattern = mux.normalizePattern(pattern)
+	if strings.HasSuffix(ri.pattern, "/") {
+		p = strings.TrimSuffix(p, "$") + "/$"
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return fmt.Errorf("invalid path pattern %s: %s", pattern, err)
+	}
+	ri.re = re
+
+	if ri.name != "" {
+		if r := mux.findRouteByName(ri.name); r != nil {
+			return fmt.Errorf("route name %s is already used by %s", ri.name, r.pattern)
+		}
 	}
 
-	mux.resources[p] = ri
+	if err := mux.checkConflicts(ri.pattern); err != nil {
+		return err
+	}
+
+	mux.composeRoute(ri)
+	t := *mux.table()
+	if err := mux.insertTree(&t, ri); err != nil {
+		return err
+	}
+	t.routes = append(t.routes[:len(t.routes):len(t.routes)], ri)
+	mux.current.Store(&t)
 	return nil
 }
 
-func (mux *Mux) handle(r *http.Request) (http.HandlerFunc, map[string]string) {
-	method := r.Method
-	path := strings.TrimRight(r.URL.Path, "/")
+// replaceRoute returns a copy of routes with old replaced by ri.
+func replaceRoute(routes []*resourceInfo, old, ri *resourceInfo) []*resourceInfo {
+	replaced := make([]*resourceInfo, len(routes))
+	for i, r := range routes {
+		if r == old {
+			r = ri
+		}
+		replaced[i] = r
+	}
+	return replaced
+}
+
+// replace serves ri instead of the registered old, which must have the same pattern.
+// The caller must hold mux.mu.
+func (mux *Mux) replace(old, ri *resourceInfo) error {
+	mux.composeRoute(ri)
+	t := *mux.table()
+	if err := mux.insertTree(&t, ri); err != nil {
+		return err
+	}
+
+	t.routes = replaceRoute(t.routes, old, ri)
+	mux.current.Store(&t)
+	return nil
+}
+
+func (mux *Mux) handle(r *http.Request, params *pathParams) http.HandlerFunc {
+	t := mux.table()
+	if len(t.hosts) > 0 {
+		if hr := matchHost(t.hosts, r.Host, params); hr != nil {
+			return hr.handler
+		}
+	}
+	if len(t.schemes) > 0 {
+		if sr := mux.matchScheme(t.schemes, r); sr != nil {
+			return sr.handler
+		}
+	}
+
+	path := r.URL.Path
+	if mux.rawPath {
+		path = r.URL.EscapedPath()
+	}
+
+	if mux.cleanPath != CleanPathNone {
+		if c := cleanPath(path); c != path {
+			if mux.cleanPath == CleanPathRedirect {
+				return t.cleanPath
+			}
+			path = c
+		}
+	}
+
+	// A mounted Mux serves the path with its trailing slash, so that its own policy applies.
+	full := path
+	if mux.trailingSlash == TrailingSlashLenient {
+		path = strings.TrimRight(path, "/")
+	}
+
+	ri := findResourceInfoByRequestPath(t.tree, path, params)
+	if ri != nil && ri.mounted {
+		// The catch-all of the mount route is not a parameter declared by any pattern.
+		rest := ""
+		if n := len(params.keys); n > 0 && params.keys[n-1] == "*" {
+			rest = params.values[n-1]
+			params.truncate(n - 1)
+		}
+		params.prefix = strings.TrimRight(strings.TrimSuffix(path, rest), "/")
+		params.rest = full[len(params.prefix):]
+		if params.rest == "" {
+			params.rest = "/"
+		}
+		if !mux.rawPath {
+			params.prefix = escapePath(params.prefix)
+		}
+	}
+	if ri != nil && mux.rawPath {
+		params.unescape()
+	}
+
+	if ri == nil && mux.trailingSlash == TrailingSlashRedirect {
+		if findResourceInfoByRequestPath(t.tree, toggleTrailingSlash(path), params) != nil {
+			params.reset()
+			return t.trailingSlash
+		}
+	}
 
-	ri, params := findResourceInfoByRequestPath(mux.resources, path)
 	if ri == nil {
-		return handleNotFound, make(map[string]string)
-	}
-
-	resource := ri.resource
-
-	var h http.HandlerFunc
-	switch method {
-	case http.MethodPost:
-		h = http.HandlerFunc(resource.Post)
-	case http.MethodGet:
-		h = http.HandlerFunc(resource.Get)
-	case http.MethodPut:
-		h = http.HandlerFunc(resource.Put)
-	case http.MethodDelete:
-		h = http.HandlerFunc(resource.Delete)
-	case http.MethodPatch:
-		h = http.HandlerFunc(resource.Patch)
-	case http.MethodOptions:
-		h = http.HandlerFunc(resource.Options)
-	case http.MethodTrace:
-		h = http.HandlerFunc(resource.Trace)
-	default:
-		h = handleMethodNotAllowed
+		return t.notFound
+	}
+
+	if h, ok := ri.chains[r.Method]; ok {
+		return h
 	}
+	return ri.fallback
+}
 
+// wrap applies the middleware added to the Mux by Use to h.
+func (mux *Mux) wrap(h http.HandlerFunc) http.HandlerFunc {
 	if len(mux.middlewares) > 0 {
 		middleware := ChainMiddleware(mux.middlewares...)
 		h = middleware(h)
 	}
-
-	return h, params
-}
-
-func pathParamKey(k string) string {
-	return fmt.Sprintf("%s%s", pathParamPrefix, k)
+	return h
 }
 
+// ServeHTTP dispatches r to the matched resource.
+// The path parameters are reused by later requests, so they must not be read after the handler returns.
 func (mux *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h, params := mux.handle(r)
-	for key, val := range params {
-		r = r.WithContext(context.WithValue(r.Context(), pathParamKey(key), val))
+	params := pathParamsPool.Get().(*pathParams)
+	params.reset()
+
+	h := mux.handle(r, params)
+	if len(params.keys) > 0 || params.rest != "" {
+		r = withPathParams(r, params)
 	}
 	h.ServeHTTP(w, r)
+
+	pathParamsPool.Put(params)
 }
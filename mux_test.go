@@ -2,6 +2,10 @@ package eagle
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -19,46 +23,120 @@ func TestFindResourceByRequestPath(t *testing.T) {
 		ResourceImpl
 	}
 	type want struct {
-		resourceInfo *resourceInfo
-		params       map[string]string
+		pattern string
+		params  map[string]string
+	}
+
+	patterns := []string{
+		"/users",
+		"/users/{id:[0-9]+}",
+		"/users/{id:[0-9]+}/posts",
+		"/users/{id:[0-9]+}/posts/{postID:[0-9]+}",
+		"/user_groups/{name:[a-z]+}",
+		"/reports/{id:[0-9]+}.json",
+		"/assets/*path",
 	}
 
 	tests := []struct {
-		name            string
-		path            string
-		resourceInfoMap func() resourceInfoMap
-		wants           want
+		name  string
+		path  string
+		wants want
 	}{
 		{
 			name: "test1",
 			path: "/users/2",
-			resourceInfoMap: func() resourceInfoMap {
-				r := &resource{}
-				ri := &resourceInfo{
-					resource: r,
-					isRegExp: true,
-				}
-				return resourceInfoMap(map[string]*resourceInfo{
-					"/users/(?P<id>[0-9]+)": ri,
-				})
+			wants: want{
+				pattern: "/users/{id:[0-9]+}",
+				params: map[string]string{
+					"id": "2",
+				},
+			},
+		},
+		{
+			name: "Static path can be found",
+			path: "/users",
+			wants: want{
+				pattern: "/users",
+				params:  map[string]string{},
+			},
+		},
+		{
+			name: "Nested path parameters can be found",
+			path: "/users/2/posts/10",
+			wants: want{
+				pattern: "/users/{id:[0-9]+}/posts/{postID:[0-9]+}",
+				params: map[string]string{
+					"id":     "2",
+					"postID": "10",
+				},
+			},
+		},
+		{
+			name: "Static prefix shared with another resource can be found",
+			path: "/user_groups/admin",
+			wants: want{
+				pattern: "/user_groups/{name:[a-z]+}",
+				params: map[string]string{
+					"name": "admin",
+				},
 			},
+		},
+		{
+			name: "Path parameter followed by a literal can be found",
+			path: "/reports/3.json",
 			wants: want{
-				resourceInfo: &resourceInfo{
-					resource: &resource{},
-					isRegExp: true,
+				pattern: "/reports/{id:[0-9]+}.json",
+				params: map[string]string{
+					"id": "3",
 				},
+			},
+		},
+		{
+			name: "Catch-all captures the rest of the path",
+			path: "/assets/css/main.css",
+			wants: want{
+				pattern: "/assets/*path",
 				params: map[string]string{
-					"id": "2",
+					"path": "css/main.css",
 				},
 			},
 		},
+		{
+			name: "Regular expression must match the whole segment",
+			path: "/users/2a",
+			wants: want{
+				params: map[string]string{},
+			},
+		},
+		{
+			name: "Unknown path can not be found",
+			path: "/posts",
+			wants: want{
+				params: map[string]string{},
+			},
+		},
+	}
+
+	tree := &node{}
+	resourceInfos := make(map[string]*resourceInfo)
+	for _, p := range patterns {
+		ri := &resourceInfo{resource: &resource{}}
+		if err := tree.insert(p, ri); err != nil {
+			t.Fatalf("insert failed pattern: %s err: %s", p, err)
+		}
+		resourceInfos[p] = ri
 	}
 
 	for _, td := range tests {
 		t.Run(td.name, func(t *testing.T) {
-			r, params := findResourceInfoByRequestPath(td.resourceInfoMap(), td.path)
-			if !reflect.DeepEqual(r, td.wants.resourceInfo) {
-				t.Errorf("findResourceInfoByRequestPath failed result %+v, expected: %+v", r, td.wants.resourceInfo)
+			ps := &pathParams{}
+			r := findResourceInfoByRequestPath(tree, td.path, ps)
+			params := make(map[string]string)
+			for i, k := range ps.keys {
+				params[k] = ps.values[i]
+			}
+			if r != resourceInfos[td.wants.pattern] {
+				t.Errorf("findResourceInfoByRequestPath failed result %+v, expected: %+v", r, resourceInfos[td.wants.pattern])
 			}
 
 			if !reflect.DeepEqual(params, td.wants.params) {
@@ -83,7 +161,7 @@ func TestGenMatchPattern(t *testing.T) {
 			name:    "Regular expression match pattern can be obtained",
 			pattern: "/users/{id:[0-9]+}",
 			want: want{
-				p:        "/users/(?P<id>[0-9]+)",
+				p:        "^/users/(?P<id>[0-9]+)$",
 				isRegExp: true,
 				err:      nil,
 			},
@@ -92,11 +170,74 @@ func TestGenMatchPattern(t *testing.T) {
 			name:    "You can get the pattern passed as argument",
 			pattern: "/users",
 			want: want{
-				p:        "/users",
+				p:        "^/users$",
+				isRegExp: false,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Static parts are quoted",
+			pattern: "/users/{id:[0-9]+}.json",
+			want: want{
+				p:        `^/users/(?P<id>[0-9]+)\.json$`,
+				isRegExp: true,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Trailing slash is removed",
+			pattern: "/users/",
+			want: want{
+				p:        "^/users$",
 				isRegExp: false,
 				err:      nil,
 			},
 		},
+		{
+			name:    "Catch-all match pattern can be obtained",
+			pattern: "/files/{dir:[a-z]+}/*path",
+			want: want{
+				p:        "^/files/(?P<dir>[a-z]+)(?:/(?P<path>.*))?$",
+				isRegExp: true,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Unnamed catch-all match pattern can be obtained",
+			pattern: "/files/*",
+			want: want{
+				p:        "^/files(?:/(.*))?$",
+				isRegExp: true,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Asterisk in a regular expression is not a catch-all",
+			pattern: "/files/{name:[a-z]*}",
+			want: want{
+				p:        "^/files/(?P<name>[a-z]*)$",
+				isRegExp: true,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Error because catch-all is not the last segment",
+			pattern: "/files/*path/raw",
+			want: want{
+				p:        "/files/*path/raw",
+				isRegExp: false,
+				err:      errors.New("catch-all parameter must be the last segment"),
+			},
+		},
+		{
+			name:    "Error because catch-all does not follow a slash",
+			pattern: "/files*path",
+			want: want{
+				p:        "/files*path",
+				isRegExp: false,
+				err:      errors.New("catch-all parameter must follow /"),
+			},
+		},
 		{
 			name:    "{There is no error so",
 			pattern: "/users/id:[0-9]+}",
@@ -121,7 +262,25 @@ func TestGenMatchPattern(t *testing.T) {
 			want: want{
 				p:        "/users/{id[0-9]+}",
 				isRegExp: false,
-				err:      errors.New("delimiters are not set in path parameters"),
+				err:      errors.New("invalid path parameter name: id[0-9]+"),
+			},
+		},
+		{
+			name:    "Parameter without regular expression matches one segment",
+			pattern: "/users/{id}",
+			want: want{
+				p:        "^/users/(?P<id>[^/]+)$",
+				isRegExp: true,
+				err:      nil,
+			},
+		},
+		{
+			name:    "Named type is expanded",
+			pattern: "/users/{id:int}/posts/{slug:slug}",
+			want: want{
+				p:        "^/users/(?P<id>[0-9]+)/posts/(?P<slug>[a-z0-9]+(?:-[a-z0-9]+)*)$",
+				isRegExp: true,
+				err:      nil,
 			},
 		},
 		{
@@ -152,3 +311,1586 @@ func TestGenMatchPattern(t *testing.T) {
 		})
 	}
 }
+
+func benchmarkFindResourceByRequestPath(b *testing.B, n int) {
+	type resource struct {
+		ResourceImpl
+	}
+
+	tree := &node{}
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("/resources%d/{id:[0-9]+}/items", i)
+		if err := tree.insert(p, &resourceInfo{resource: &resource{}}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	path := fmt.Sprintf("/resources%d/10/items", n-1)
+	ps := &pathParams{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.reset()
+		if ri := findResourceInfoByRequestPath(tree, path, ps); ri == nil {
+			b.Fatal("resource not found")
+		}
+	}
+}
+
+func BenchmarkFindResourceByRequestPath10(b *testing.B) {
+	benchmarkFindResourceByRequestPath(b, 10)
+}
+
+func BenchmarkFindResourceByRequestPath100(b *testing.B) {
+	benchmarkFindResourceByRequestPath(b, 100)
+}
+
+func BenchmarkFindResourceByRequestPath1000(b *testing.B) {
+	benchmarkFindResourceByRequestPath(b, 1000)
+}
+
+type namedResource struct {
+	ResourceImpl
+	name string
+}
+
+func (nr *namedResource) Get(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(nr.name))
+}
+
+func TestMuxRoutePrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     string
+	}{
+		{
+			name:     "Static beats parameterised",
+			patterns: []string{"/users/{id:[a-z]+}", "/users/me"},
+			path:     "/users/me",
+			want:     "/users/me",
+		},
+		{
+			name:     "Static beats parameterised regardless of registration order",
+			patterns: []string{"/users/me", "/users/{id:[a-z]+}"},
+			path:     "/users/me",
+			want:     "/users/me",
+		},
+		{
+			name:     "Parameterised is used when static does not match",
+			patterns: []string{"/users/me", "/users/{id:[a-z]+}"},
+			path:     "/users/you",
+			want:     "/users/{id:[a-z]+}",
+		},
+		{
+			name:     "Parameterised beats catch-all",
+			patterns: []string{"/files/*path", "/files/{name:[a-z]+}"},
+			path:     "/files/readme",
+			want:     "/files/{name:[a-z]+}",
+		},
+		{
+			name:     "Longer literal beats shorter",
+			patterns: []string{"/files/{name:[a-z]+}", "/files/img{name:[a-z]+}"},
+			path:     "/files/imgfoo",
+			want:     "/files/img{name:[a-z]+}",
+		},
+		{
+			name:     "Parameter followed by a literal beats parameter spanning the segment",
+			patterns: []string{"/files/{name:[a-z.]+}", "/files/{name:[a-z]+}.json"},
+			path:     "/files/foo.json",
+			want:     "/files/{name:[a-z]+}.json",
+		},
+		{
+			name:     "First registered wins between parameters",
+			patterns: []string{"/users/{id:[0-9]+}", "/users/{name:[0-9a-z]+}"},
+			path:     "/users/10",
+			want:     "/users/{id:[0-9]+}",
+		},
+		{
+			name:     "First registered wins between parameters in reverse order",
+			patterns: []string{"/users/{name:[0-9a-z]+}", "/users/{id:[0-9]+}"},
+			path:     "/users/10",
+			want:     "/users/{name:[0-9a-z]+}",
+		},
+		{
+			name:     "Falls back to parameterised when static branch does not match the rest",
+			patterns: []string{"/users/me/posts", "/users/{id:[a-z]+}/likes"},
+			path:     "/users/me/likes",
+			want:     "/users/{id:[a-z]+}/likes",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				router := NewRouter()
+				for _, p := range td.patterns {
+					if err := router.SetResource(p, &namedResource{name: p}); err != nil {
+						t.Fatalf("SetResource failed pattern: %s err: %s", p, err)
+					}
+				}
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+				if w.Body.String() != td.want {
+					t.Fatalf("route precedence failed result: %s, expected: %s", w.Body.String(), td.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetResourceMatchPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			name:    "Pattern is anchored to the whole path",
+			pattern: "/users/{id:[0-9]+}",
+			match:   []string{"/users/3"},
+			noMatch: []string{"/admin/users/3/delete", "/users/3/delete", "/users/3a"},
+		},
+		{
+			name:    "Static pattern is matched literally",
+			pattern: "/users.json",
+			match:   []string{"/users.json"},
+			noMatch: []string{"/usersxjson"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			if err := router.SetResource(td.pattern, &namedResource{}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			ri := findResourceInfoByRequestPath(router.table().tree, td.match[0], &pathParams{})
+			if ri == nil {
+				t.Fatalf("resource is not found path: %s", td.match[0])
+			}
+
+			for _, path := range td.match {
+				if r := findResourceInfoByRequestPath(router.table().tree, path, &pathParams{}); r == nil {
+					t.Errorf("resource is not found path: %s", path)
+				}
+				if !ri.re.MatchString(path) {
+					t.Errorf("compiled pattern %s does not match path: %s", ri.re, path)
+				}
+			}
+
+			for _, path := range td.noMatch {
+				if r := findResourceInfoByRequestPath(router.table().tree, path, &pathParams{}); r != nil {
+					t.Errorf("resource is found path: %s", path)
+				}
+				if ri.re.MatchString(path) {
+					t.Errorf("compiled pattern %s matches path: %s", ri.re, path)
+				}
+			}
+		})
+	}
+}
+
+func TestSetResourceInvalidRegExp(t *testing.T) {
+	router := NewRouter()
+	err := router.SetResource("/users/{id:[0-9+}", &namedResource{})
+	if err == nil {
+		t.Fatal("SetResource must return an error for an invalid regular expression")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+}
+
+type writableResource struct {
+	namedResource
+}
+
+func (wr *writableResource) Post(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (wr *writableResource) Delete(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestMuxMethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource Resource
+		method   string
+		code     int
+		allow    string
+	}{
+		{
+			name:     "Implemented method is dispatched",
+			resource: &namedResource{},
+			method:   http.MethodGet,
+			code:     http.StatusOK,
+		},
+		{
+			name:     "Method the resource does not implement is not allowed",
+			resource: &namedResource{},
+			method:   http.MethodPost,
+			code:     http.StatusMethodNotAllowed,
+			allow:    "GET, HEAD, OPTIONS",
+		},
+		{
+			name:     "Allow header lists every implemented method",
+			resource: &writableResource{},
+			method:   http.MethodPut,
+			code:     http.StatusMethodNotAllowed,
+			allow:    "GET, HEAD, POST, DELETE, OPTIONS",
+		},
+		{
+			name:     "Method of an embedding type is dispatched",
+			resource: &writableResource{},
+			method:   http.MethodDelete,
+			code:     http.StatusNoContent,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			if err := router.SetResource("/things", td.resource); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, "/things", nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if allow := w.Header().Get("Allow"); allow != td.allow {
+				t.Errorf("Allow header result: %s, expected: %s", allow, td.allow)
+			}
+		})
+	}
+}
+
+func TestSetResourceWithoutMethod(t *testing.T) {
+	type resource struct {
+		ResourceImpl
+	}
+
+	router := NewRouter()
+	if err := router.SetResource("/things", &resource{}); err == nil {
+		t.Error("SetResource must return an error for a resource without any method")
+	}
+}
+
+type optionsResource struct {
+	namedResource
+}
+
+func (or *optionsResource) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", "GET")
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestMuxDefaultHeadAndOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource Resource
+		method   string
+		code     int
+		allow    string
+		body     string
+	}{
+		{
+			name:     "HEAD runs GET",
+			resource: &namedResource{name: "things"},
+			method:   http.MethodHead,
+			code:     http.StatusOK,
+			body:     "things",
+		},
+		{
+			name:     "HEAD is not allowed without GET",
+			resource: &onlyPostResource{},
+			method:   http.MethodHead,
+			code:     http.StatusMethodNotAllowed,
+			allow:    "POST, OPTIONS",
+		},
+		{
+			name:     "OPTIONS answers the implemented methods",
+			resource: &writableResource{},
+			method:   http.MethodOptions,
+			code:     http.StatusNoContent,
+			allow:    "GET, HEAD, POST, DELETE, OPTIONS",
+		},
+		{
+			name:     "OPTIONS can be overridden by the resource",
+			resource: &optionsResource{},
+			method:   http.MethodOptions,
+			code:     http.StatusOK,
+			allow:    "GET",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			if err := router.SetResource("/things", td.resource); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, "/things", nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if allow := w.Header().Get("Allow"); allow != td.allow {
+				t.Errorf("Allow header result: %s, expected: %s", allow, td.allow)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+		})
+	}
+}
+
+type flushResource struct{}
+
+func (fr *flushResource) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := w.(http.Flusher); !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte("flushable things"))
+}
+
+func TestMuxDefaultHeadOverServer(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/things", &flushResource{}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		t.Run(method, func(t *testing.T) {
+			req, err := http.NewRequest(method, server.URL+"/things", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status code result: %d, expected: %d", resp.StatusCode, http.StatusOK)
+			}
+
+			if cl := resp.Header.Get("Content-Length"); cl != "16" {
+				t.Errorf("Content-Length result: %s, expected: %s", cl, "16")
+			}
+
+			want := "flushable things"
+			if method == http.MethodHead {
+				want = ""
+			}
+			if string(body) != want {
+				t.Errorf("body result: %s, expected: %s", body, want)
+			}
+		})
+	}
+}
+
+type onlyPostResource struct{}
+
+func (or *onlyPostResource) Post(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+}
+
+func recordMiddleware(name string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Middleware", name)
+			next(w, r)
+		}
+	}
+}
+
+func TestMuxResourceMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+	err := router.SetResource("/things", &writableResource{},
+		WithMiddleware(recordMiddleware("resource")),
+		WithMethodMiddleware(http.MethodGet, recordMiddleware("get")),
+		WithMethodMiddleware(http.MethodOptions, recordMiddleware("options")),
+	)
+	if err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	// Merging a method recreates the default handlers.
+	if err := router.HandleFunc(http.MethodPut, "/things", func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+	if err := router.SetResource("/others", &namedResource{}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := router.SetResource("/heads", &namedResource{}, WithMethodMiddleware(http.MethodHead, recordMiddleware("head"))); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   []string
+	}{
+		{
+			name:   "Method middleware runs after resource middleware",
+			method: http.MethodGet,
+			path:   "/things",
+			want:   []string{"global", "resource", "get"},
+		},
+		{
+			name:   "GET middleware applies to the default HEAD handler",
+			method: http.MethodHead,
+			path:   "/things",
+			want:   []string{"global", "resource", "get"},
+		},
+		{
+			name:   "OPTIONS middleware applies to the default OPTIONS handler",
+			method: http.MethodOptions,
+			path:   "/things",
+			want:   []string{"global", "resource", "options"},
+		},
+		{
+			name:   "HEAD middleware applies to the default HEAD handler",
+			method: http.MethodHead,
+			path:   "/heads",
+			want:   []string{"global", "head"},
+		},
+		{
+			name:   "Method middleware does not run for other methods",
+			method: http.MethodPost,
+			path:   "/things",
+			want:   []string{"global", "resource"},
+		},
+		{
+			name:   "Resource middleware does not run for other resources",
+			method: http.MethodGet,
+			path:   "/others",
+			want:   []string{"global"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, td.path, nil))
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, td.want) {
+				t.Errorf("middleware result: %v, expected: %v", got, td.want)
+			}
+		})
+	}
+}
+
+func TestSetResourceMethodMiddlewareForUnimplementedMethod(t *testing.T) {
+	router := NewRouter()
+	err := router.SetResource("/things", &namedResource{}, WithMethodMiddleware(http.MethodPut, recordMiddleware("put")))
+	if err == nil {
+		t.Error("SetResource must return an error for middleware of a method the resource does not implement")
+	}
+
+	err = router.SetResource("/others", &onlyPostResource{}, WithMethodMiddleware(http.MethodHead, recordMiddleware("head")))
+	if err == nil {
+		t.Error("SetResource must return an error for middleware of HEAD without GET")
+	}
+}
+
+func TestMuxHandle(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+
+	err := router.Handle("/debug/{name:[a-z]+}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + PathParam(r, "name")))
+	}))
+	if err != nil {
+		t.Fatalf("Handle failed err: %s", err)
+	}
+
+	err = router.HandleFunc(http.MethodGet, "/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("get " + PathParam(r, "id")))
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	err = router.HandleFunc(http.MethodPut, "/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("put " + PathParam(r, "id")))
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	for _, method := range []string{"PROPFIND", "MKCOL"} {
+		err = router.HandleFunc(method, "/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Method + " " + PathParam(r, "id")))
+		})
+		if err != nil {
+			t.Fatalf("HandleFunc failed err: %s", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+		allow  string
+	}{
+		{
+			name:   "Handler is served for every method",
+			method: http.MethodPost,
+			path:   "/debug/pprof",
+			code:   http.StatusOK,
+			body:   "POST pprof",
+		},
+		{
+			name:   "Handler function is served for its method",
+			method: http.MethodGet,
+			path:   "/users/1",
+			code:   http.StatusOK,
+			body:   "get 1",
+		},
+		{
+			name:   "Handler functions under the same pattern are served together",
+			method: http.MethodPut,
+			path:   "/users/1",
+			code:   http.StatusOK,
+			body:   "put 1",
+		},
+		{
+			name:   "Method without handler function is not allowed",
+			method: http.MethodDelete,
+			path:   "/users/1",
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, HEAD, PUT, OPTIONS, MKCOL, PROPFIND",
+		},
+		{
+			name:   "Extension method is served",
+			method: "PROPFIND",
+			path:   "/users/1",
+			code:   http.StatusOK,
+			body:   "PROPFIND 1",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, td.path, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+
+			if allow := w.Header().Get("Allow"); allow != td.allow {
+				t.Errorf("Allow header result: %s, expected: %s", allow, td.allow)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, []string{"global"}) {
+				t.Errorf("middleware result: %v, expected: %v", got, []string{"global"})
+			}
+		})
+	}
+}
+
+func TestMuxHandleFuncInvalidMethod(t *testing.T) {
+	tests := []string{"", "get", "Get", "GET POST", "GET/1"}
+
+	for _, method := range tests {
+		t.Run(method, func(t *testing.T) {
+			router := NewRouter()
+			err := router.HandleFunc(method, "/things", func(w http.ResponseWriter, r *http.Request) {})
+			if err == nil {
+				t.Errorf("HandleFunc must return an error for method: %q", method)
+			}
+		})
+	}
+}
+
+func TestMuxNotFoundAndMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		RenderJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		RenderJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "allowed: " + w.Header().Get("Allow")})
+	})
+	if err := router.SetResource("/things", &namedResource{}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{
+			name:   "Custom NotFound handler is used",
+			method: http.MethodGet,
+			path:   "/others",
+			code:   http.StatusNotFound,
+			body:   `{"message":"not found"}` + "\n",
+		},
+		{
+			name:   "Custom MethodNotAllowed handler is used",
+			method: http.MethodPost,
+			path:   "/things",
+			code:   http.StatusMethodNotAllowed,
+			body:   `{"message":"allowed: GET, HEAD, OPTIONS"}` + "\n",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, td.path, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, []string{"global"}) {
+				t.Errorf("middleware result: %v, expected: %v", got, []string{"global"})
+			}
+		})
+	}
+}
+
+func TestMuxCatchAll(t *testing.T) {
+	router := NewRouter()
+	err := router.HandleFunc(http.MethodGet, "/files/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("files:" + PathParam(r, "path")))
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+	if err := router.SetResource("/static", &namedResource{name: "static"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	err = router.HandleFunc(http.MethodGet, "/static/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("static:" + PathParam(r, "*")))
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{
+			name: "Catch-all captures the rest of the path including slashes",
+			path: "/files/css/main.css",
+			code: http.StatusOK,
+			body: "files:css/main.css",
+		},
+		{
+			name: "Trailing slash is removed like from any other path",
+			path: "/files/docs/",
+			code: http.StatusOK,
+			body: "files:docs",
+		},
+		{
+			name: "Catch-all matches an empty rest",
+			path: "/files/",
+			code: http.StatusOK,
+			body: "files:",
+		},
+		{
+			name: "Catch-all matches the path without it",
+			path: "/files",
+			code: http.StatusOK,
+			body: "files:",
+		},
+		{
+			name: "Resource registered at the path without catch-all is kept",
+			path: "/static",
+			code: http.StatusOK,
+			body: "static",
+		},
+		{
+			name: "Unnamed catch-all is captured as *",
+			path: "/static/img/logo.png",
+			code: http.StatusOK,
+			body: "static:img/logo.png",
+		},
+		{
+			name: "Path sharing only a prefix is not found",
+			path: "/filesystem",
+			code: http.StatusNotFound,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+		})
+	}
+}
+
+func TestMuxParamTypes(t *testing.T) {
+	router := NewRouter()
+	patterns := []string{
+		"/users/{id:int}",
+		"/users/{id:uuid}",
+		"/posts/{slug:slug}",
+		"/tags/{name}",
+	}
+	for _, p := range patterns {
+		if err := router.SetResource(p, &namedResource{name: p}); err != nil {
+			t.Fatalf("SetResource failed pattern: %s err: %s", p, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "int matches digits",
+			path: "/users/10",
+			want: "/users/{id:int}",
+		},
+		{
+			name: "uuid matches a UUID",
+			path: "/users/0f8fad5b-d9cb-469f-a165-70867728950e",
+			want: "/users/{id:uuid}",
+		},
+		{
+			name: "uuid does not match a malformed UUID",
+			path: "/users/0f8fad5b-d9cb-469f",
+		},
+		{
+			name: "slug matches hyphenated words",
+			path: "/posts/hello-world-2",
+			want: "/posts/{slug:slug}",
+		},
+		{
+			name: "slug does not match consecutive hyphens",
+			path: "/posts/hello--world",
+		},
+		{
+			name: "Parameter without regular expression matches any segment",
+			path: "/tags/Go%20lang",
+			want: "/tags/{name}",
+		},
+		{
+			name: "Parameter without regular expression does not match slashes",
+			path: "/tags/go/lang",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+			if w.Body.String() != td.want {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.want)
+			}
+		})
+	}
+}
+
+type nopResponseWriter struct {
+	header http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *nopResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *nopResponseWriter) WriteHeader(statusCode int) {}
+
+func benchmarkMuxServeHTTP(b *testing.B, pattern, path string) {
+	router := NewRouter()
+	err := router.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) {})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	w := &nopResponseWriter{header: make(http.Header)}
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkMuxServeHTTPStatic(b *testing.B) {
+	benchmarkMuxServeHTTP(b, "/users/me", "/users/me")
+}
+
+func BenchmarkMuxServeHTTPParams(b *testing.B) {
+	benchmarkMuxServeHTTP(b, "/users/{id:int}/posts/{postID:int}", "/users/1/posts/2")
+}
+
+func TestMuxServeHTTPStaticAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted under the race detector")
+	}
+
+	router := NewRouter()
+	err := router.HandleFunc(http.MethodGet, "/users/me", func(w http.ResponseWriter, r *http.Request) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &nopResponseWriter{header: make(http.Header)}
+	r := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	router.ServeHTTP(w, r)
+
+	if n := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, r) }); n != 0 {
+		t.Errorf("ServeHTTP allocations result: %v, expected: 0", n)
+	}
+}
+
+func TestMuxTrailingSlash(t *testing.T) {
+	type request struct {
+		method   string
+		target   string
+		code     int
+		body     string
+		location string
+	}
+
+	tests := []struct {
+		name     string
+		policy   TrailingSlashPolicy
+		requests []request
+	}{
+		{
+			name:   "Lenient policy ignores trailing slashes",
+			policy: TrailingSlashLenient,
+			requests: []request{
+				{method: http.MethodGet, target: "/things", code: http.StatusOK, body: "/things"},
+				{method: http.MethodGet, target: "/things/", code: http.StatusOK, body: "/things"},
+				{method: http.MethodGet, target: "/dirs", code: http.StatusOK, body: "/dirs/"},
+				{method: http.MethodGet, target: "/dirs/", code: http.StatusOK, body: "/dirs/"},
+			},
+		},
+		{
+			name:   "Strict policy matches trailing slashes exactly",
+			policy: TrailingSlashStrict,
+			requests: []request{
+				{method: http.MethodGet, target: "/things", code: http.StatusOK, body: "/things"},
+				{method: http.MethodGet, target: "/things/", code: http.StatusNotFound},
+				{method: http.MethodGet, target: "/dirs", code: http.StatusNotFound},
+				{method: http.MethodGet, target: "/dirs/", code: http.StatusOK, body: "/dirs/"},
+				{method: http.MethodGet, target: "/", code: http.StatusOK, body: "/"},
+			},
+		},
+		{
+			name:   "Redirect policy redirects to the registered form",
+			policy: TrailingSlashRedirect,
+			requests: []request{
+				{method: http.MethodGet, target: "/things", code: http.StatusOK, body: "/things"},
+				{method: http.MethodGet, target: "/things/?page=2", code: http.StatusMovedPermanently, location: "/things?page=2"},
+				{method: http.MethodGet, target: "/dirs", code: http.StatusMovedPermanently, location: "/dirs/"},
+				{method: http.MethodPost, target: "/dirs", code: http.StatusPermanentRedirect, location: "/dirs/"},
+				{method: http.MethodGet, target: "/others/", code: http.StatusNotFound},
+			},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(WithTrailingSlash(td.policy))
+			for _, p := range []string{"/", "/things", "/dirs/"} {
+				if err := router.SetResource(p, &writableResource{namedResource{name: p}}); err != nil {
+					t.Fatalf("SetResource failed pattern: %s err: %s", p, err)
+				}
+			}
+
+			for _, req := range td.requests {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(req.method, req.target, nil))
+				if w.Code != req.code {
+					t.Errorf("%s %s status code result: %d, expected: %d", req.method, req.target, w.Code, req.code)
+				}
+
+				if req.body != "" && w.Body.String() != req.body {
+					t.Errorf("%s %s body result: %s, expected: %s", req.method, req.target, w.Body.String(), req.body)
+				}
+
+				if location := w.Header().Get("Location"); location != req.location {
+					t.Errorf("%s %s Location header result: %s, expected: %s", req.method, req.target, location, req.location)
+				}
+			}
+		})
+	}
+}
+
+func TestMuxMountTrailingSlash(t *testing.T) {
+	type request struct {
+		target   string
+		code     int
+		location string
+	}
+
+	tests := []struct {
+		name     string
+		policy   TrailingSlashPolicy
+		requests []request
+	}{
+		{
+			name:   "Lenient mounted Mux",
+			policy: TrailingSlashLenient,
+			requests: []request{
+				{target: "/api/things", code: http.StatusOK},
+				{target: "/api/things/", code: http.StatusOK},
+				{target: "/api/dirs", code: http.StatusOK},
+				{target: "/api/dirs/", code: http.StatusOK},
+			},
+		},
+		{
+			name:   "Strict mounted Mux",
+			policy: TrailingSlashStrict,
+			requests: []request{
+				{target: "/api/things", code: http.StatusOK},
+				{target: "/api/things/", code: http.StatusNotFound},
+				{target: "/api/dirs", code: http.StatusNotFound},
+				{target: "/api/dirs/", code: http.StatusOK},
+			},
+		},
+		{
+			name:   "Redirecting mounted Mux",
+			policy: TrailingSlashRedirect,
+			requests: []request{
+				{target: "/api/things", code: http.StatusOK},
+				{target: "/api/things/", code: http.StatusMovedPermanently, location: "/api/things"},
+				{target: "/api/dirs", code: http.StatusMovedPermanently, location: "/api/dirs/"},
+				{target: "/api/dirs/", code: http.StatusOK},
+			},
+		},
+	}
+
+	parents := map[string]TrailingSlashPolicy{
+		"lenient":  TrailingSlashLenient,
+		"strict":   TrailingSlashStrict,
+		"redirect": TrailingSlashRedirect,
+	}
+
+	for _, td := range tests {
+		for parentName, parentPolicy := range parents {
+			t.Run(td.name+" in "+parentName+" Mux", func(t *testing.T) {
+				sub := NewRouter(WithTrailingSlash(td.policy))
+				for _, p := range []string{"/things", "/dirs/"} {
+					if err := sub.SetResource(p, &namedResource{name: p}); err != nil {
+						t.Fatalf("SetResource failed pattern: %s err: %s", p, err)
+					}
+				}
+
+				router := NewRouter(WithTrailingSlash(parentPolicy))
+				if err := router.Mount("/api", sub); err != nil {
+					t.Fatalf("Mount failed err: %s", err)
+				}
+
+				for _, req := range td.requests {
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, req.target, nil))
+					if w.Code != req.code {
+						t.Errorf("status code result: %d, expected: %d target: %s", w.Code, req.code, req.target)
+					}
+
+					if location := w.Header().Get("Location"); location != req.location {
+						t.Errorf("Location header result: %s, expected: %s target: %s", location, req.location, req.target)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestMuxMiddlewareAddedAfterRegistration(t *testing.T) {
+	router := NewRouter()
+	admin := router.Group("/admin")
+	if err := admin.SetResource("/things", &namedResource{}, WithMiddleware(recordMiddleware("resource"))); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	router.Use(recordMiddleware("global"))
+	admin.Use(recordMiddleware("admin"))
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		want []string
+	}{
+		{
+			name: "Middleware added later applies to registered resources",
+			path: "/admin/things",
+			code: http.StatusOK,
+			want: []string{"global", "admin", "resource"},
+		},
+		{
+			name: "NotFound handler set later runs with the global middleware",
+			path: "/missing",
+			code: http.StatusTeapot,
+			want: []string{"global"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, td.path, nil)
+			router.ServeHTTP(w, r)
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, td.want) {
+				t.Errorf("middleware result: %v, expected: %v", got, td.want)
+			}
+		})
+	}
+}
+
+func TestChainMiddlewareBuildsOnce(t *testing.T) {
+	built := 0
+	mw := func(next http.HandlerFunc) http.HandlerFunc {
+		built++
+		return next
+	}
+
+	h := ChainMiddleware(mw, mw)(func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 3; i++ {
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	if built != 2 {
+		t.Errorf("built result: %d, expected: %d", built, 2)
+	}
+}
+
+func passMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r)
+	}
+}
+
+// newMiddlewareRouter returns a Mux serving path through global, group and resource middleware.
+func newMiddlewareRouter(tb testing.TB, pattern string) *Mux {
+	router := NewRouter()
+	router.Use(passMiddleware)
+	router.Use(passMiddleware)
+	g := router.Group("", passMiddleware)
+	err := g.SetResource(pattern, &namedResource{}, WithMiddleware(passMiddleware))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return router
+}
+
+func TestMuxServeHTTPMiddlewareAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted under the race detector")
+	}
+
+	router := newMiddlewareRouter(t, "/users/me")
+	w := &nopResponseWriter{header: make(http.Header)}
+	r := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	router.ServeHTTP(w, r)
+
+	if n := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, r) }); n != 0 {
+		t.Errorf("ServeHTTP allocations result: %v, expected: 0", n)
+	}
+}
+
+func benchmarkMuxServeHTTPMiddleware(b *testing.B, pattern, path string) {
+	router := newMiddlewareRouter(b, pattern)
+	w := &nopResponseWriter{header: make(http.Header)}
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkMuxServeHTTPMiddlewareStatic(b *testing.B) {
+	benchmarkMuxServeHTTPMiddleware(b, "/users/me", "/users/me")
+}
+
+func BenchmarkMuxServeHTTPMiddlewareParams(b *testing.B) {
+	benchmarkMuxServeHTTPMiddleware(b, "/users/{id:int}", "/users/1")
+}
+
+func TestMuxRemoveResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		remove   string
+		wantErr  bool
+		found    []string
+		notFound []string
+	}{
+		{
+			name:     "Removed resource is not found",
+			patterns: []string{"/users", "/users/{id:int}"},
+			remove:   "/users/{id:int}",
+			found:    []string{"/users"},
+			notFound: []string{"/users/1"},
+		},
+		{
+			name:     "Removing a catch-all also removes the path without it",
+			patterns: []string{"/files/*path", "/files/readme"},
+			remove:   "/files/*path",
+			found:    []string{"/files/readme"},
+			notFound: []string{"/files", "/files/a/b"},
+		},
+		{
+			name:     "Trailing slash of pattern is ignored",
+			patterns: []string{"/users"},
+			remove:   "/users/",
+			notFound: []string{"/users"},
+		},
+		{
+			name:     "Pattern not registered",
+			patterns: []string{"/users"},
+			remove:   "/users/{id:int}",
+			wantErr:  true,
+			found:    []string{"/users"},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			for _, p := range td.patterns {
+				if err := router.SetResource(p, &namedResource{name: p}); err != nil {
+					t.Fatalf("SetResource failed err: %s", err)
+				}
+			}
+
+			if err := router.RemoveResource(td.remove); (err != nil) != td.wantErr {
+				t.Fatalf("RemoveResource failed err: %s", err)
+			}
+
+			for _, path := range td.found {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("status code result: %d, expected: %d path: %s", w.Code, http.StatusOK, path)
+				}
+			}
+
+			for _, path := range td.notFound {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				if w.Code != http.StatusNotFound {
+					t.Errorf("status code result: %d, expected: %d path: %s", w.Code, http.StatusNotFound, path)
+				}
+			}
+		})
+	}
+}
+
+func TestMuxRemoveResourceAllowsRegistration(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/users/{id}", &namedResource{name: "old"}, WithName("user")); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := router.RemoveResource("/users/{id}"); err != nil {
+		t.Fatalf("RemoveResource failed err: %s", err)
+	}
+	if err := router.SetResource("/users/{userID}", &namedResource{name: "new"}, WithName("user")); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Body.String() != "new" {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), "new")
+	}
+}
+
+func TestMuxRemoveMountedResource(t *testing.T) {
+	for _, prefix := range []string{"/api", "/api/", "/api/*"} {
+		t.Run(prefix, func(t *testing.T) {
+			sub := NewRouter()
+			if err := sub.SetResource("/users", &namedResource{name: "users"}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			router := NewRouter()
+			if err := router.Mount("/api", sub); err != nil {
+				t.Fatalf("Mount failed err: %s", err)
+			}
+			if err := router.RemoveResource(prefix); err != nil {
+				t.Fatalf("RemoveResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestMuxReplaceResource(t *testing.T) {
+	router := NewRouter()
+	api := router.Group("/api", recordMiddleware("group"))
+	err := api.SetResource("/things/{id:int}", &namedResource{name: "old"},
+		WithName("thing"),
+		WithMiddleware(recordMiddleware("resource")),
+	)
+	if err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	if err := router.ReplaceResource("/api/things/{id:int}", &onlyPostResource{}); err != nil {
+		t.Fatalf("ReplaceResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		code   int
+		allow  string
+	}{
+		{
+			name:   "Method of the new resource is served",
+			method: http.MethodPost,
+			code:   http.StatusCreated,
+		},
+		{
+			name:   "Method of the old resource is not allowed",
+			method: http.MethodGet,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "POST, OPTIONS",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, "/api/things/1", nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if allow := w.Header().Get("Allow"); allow != td.allow {
+				t.Errorf("Allow header result: %s, expected: %s", allow, td.allow)
+			}
+
+			want := []string{"group", "resource"}
+			if got := w.Header()["X-Middleware"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("middleware result: %v, expected: %v", got, want)
+			}
+		})
+	}
+
+	if u, err := router.URL("thing", "id", "1"); err != nil || u != "/api/things/1" {
+		t.Errorf("URL result: %s %v, expected: %s", u, err, "/api/things/1")
+	}
+
+	if err := router.ReplaceResource("/api/others", &writableResource{}); err == nil {
+		t.Error("ReplaceResource must return an error for a pattern not registered")
+	}
+}
+
+func TestMuxReplaceResourceKeepsHandleFunc(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/users", &namedResource{name: "old"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	err := router.HandleFunc(http.MethodDelete, "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	if err := router.ReplaceResource("/users", &namedResource{name: "new"}); err != nil {
+		t.Fatalf("ReplaceResource failed err: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		code   int
+		body   string
+		allow  string
+	}{
+		{
+			name:   "Method of the new resource is served",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "new",
+		},
+		{
+			name:   "Method registered by HandleFunc is kept",
+			method: http.MethodDelete,
+			code:   http.StatusNoContent,
+		},
+		{
+			name:   "Allow lists both",
+			method: http.MethodOptions,
+			code:   http.StatusNoContent,
+			allow:  "GET, HEAD, DELETE, OPTIONS",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(td.method, "/users", nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+			if allow := w.Header().Get("Allow"); allow != td.allow {
+				t.Errorf("Allow header result: %s, expected: %s", allow, td.allow)
+			}
+		})
+	}
+
+	if err := router.ReplaceResource("/users", &deletableResource{}); err == nil {
+		t.Error("ReplaceResource must return an error for a method registered by HandleFunc")
+	}
+}
+
+type deletableResource struct{}
+
+func (dr *deletableResource) Delete(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type blockingResource struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (br *blockingResource) Get(w http.ResponseWriter, r *http.Request) {
+	close(br.started)
+	<-br.release
+	w.Write([]byte(br.name))
+}
+
+func TestMuxReplaceResourceInFlight(t *testing.T) {
+	router := NewRouter()
+	old := &blockingResource{name: "old", started: make(chan struct{}), release: make(chan struct{})}
+	if err := router.SetResource("/things", old); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things", nil))
+		close(done)
+	}()
+	<-old.started
+
+	if err := router.ReplaceResource("/things", &namedResource{name: "new"}); err != nil {
+		t.Fatalf("ReplaceResource failed err: %s", err)
+	}
+
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/things", nil))
+	if w2.Body.String() != "new" {
+		t.Errorf("body result: %s, expected: %s", w2.Body.String(), "new")
+	}
+
+	close(old.release)
+	<-done
+	if w.Body.String() != "old" {
+		t.Errorf("in-flight body result: %s, expected: %s", w.Body.String(), "old")
+	}
+}
+
+func TestMuxSpanningParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     string
+		params   []Param
+	}{
+		{
+			name:     "Parameter matching slashes",
+			patterns: []string{"/files/{p:.+}"},
+			path:     "/files/a/b",
+			want:     "/files/{p:.+}",
+			params:   []Param{{Key: "p", Value: "a/b"}},
+		},
+		{
+			name:     "Parameter matching the following literal",
+			patterns: []string{"/r/{n:[a-z.]+}.json"},
+			path:     "/r/a.b.json",
+			want:     "/r/{n:[a-z.]+}.json",
+			params:   []Param{{Key: "n", Value: "a.b"}},
+		},
+		{
+			name:     "Longest value that lets the rest match",
+			patterns: []string{"/files/{p:.+}/raw"},
+			path:     "/files/a/raw/raw",
+			want:     "/files/{p:.+}/raw",
+			params:   []Param{{Key: "p", Value: "a/raw"}},
+		},
+		{
+			name:     "Registration order among parameters",
+			patterns: []string{"/files/{name}", "/files/{p:.+}"},
+			path:     "/files/a",
+			want:     "/files/{name}",
+			params:   []Param{{Key: "name", Value: "a"}},
+		},
+		{
+			name:     "Next parameter is tried when the first does not match the rest",
+			patterns: []string{"/files/{name}", "/files/{p:.+}"},
+			path:     "/files/a/b",
+			want:     "/files/{p:.+}",
+			params:   []Param{{Key: "p", Value: "a/b"}},
+		},
+		{
+			name:     "Static segment beats spanning parameter",
+			patterns: []string{"/files/{p:.+}", "/files/readme"},
+			path:     "/files/readme",
+			want:     "/files/readme",
+		},
+		{
+			name:     "Spanning parameter beats catch-all with shorter static prefix",
+			patterns: []string{"/*rest", "/files/{p:.+}"},
+			path:     "/files/a/b",
+			want:     "/files/{p:.+}",
+			params:   []Param{{Key: "p", Value: "a/b"}},
+		},
+		{
+			name:     "Typed parameter followed by a literal beats whole segment parameter",
+			patterns: []string{"/posts/{x}", "/posts/{a:slug}-{b:int}"},
+			path:     "/posts/my-post-12",
+			want:     "/posts/{a:slug}-{b:int}",
+			params:   []Param{{Key: "a", Value: "my-post"}, {Key: "b", Value: "12"}},
+		},
+		{
+			name:     "Whole segment parameter when the literal does not match",
+			patterns: []string{"/posts/{a:slug}-{b:int}", "/posts/{x}"},
+			path:     "/posts/my-post",
+			want:     "/posts/{x}",
+			params:   []Param{{Key: "x", Value: "my-post"}},
+		},
+		{
+			name:     "Registration order among spanning patterns",
+			patterns: []string{"/files/{p:.+}", "/files/{q:[a-z/]+}/raw"},
+			path:     "/files/a/b/raw",
+			want:     "/files/{p:.+}",
+			params:   []Param{{Key: "p", Value: "a/b/raw"}},
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter()
+			for _, p := range td.patterns {
+				err := router.HandleFunc(http.MethodGet, p, func(p string) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						if !reflect.DeepEqual(Params(r), td.params) {
+							t.Errorf("params result: %v, expected: %v", Params(r), td.params)
+						}
+						w.Write([]byte(p))
+					}
+				}(p))
+				if err != nil {
+					t.Fatalf("HandleFunc failed err: %s", err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.path, nil))
+			if w.Body.String() != td.want {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.want)
+			}
+
+			ri := router.findRoute(td.want)
+			if !ri.re.MatchString(td.path) {
+				t.Errorf("compiled pattern %s does not match path: %s", ri.re, td.path)
+			}
+		})
+	}
+}
+
+func TestMuxReplaceSpanningResource(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/files/{p:.+}", &namedResource{name: "old"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := router.ReplaceResource("/files/{p:.+}", &namedResource{name: "new"}); err != nil {
+		t.Fatalf("ReplaceResource failed err: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/a/b", nil))
+	if w.Body.String() != "new" {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), "new")
+	}
+
+	if err := router.RemoveResource("/files/{p:.+}"); err != nil {
+		t.Fatalf("RemoveResource failed err: %s", err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/a/b", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,127 @@
+package eagle
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// contextKey is the type of the context keys of this package so that they never collide with keys of other packages.
+type contextKey string
+
+const pathParamsKey contextKey = "EaglePathParams"
+
+// Param is a path parameter captured from the request path.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params returns all path parameters of r in the order they appear in the path.
+// Parameters captured by a Mux that r was mounted on come first.
+func Params(r *http.Request) []Param {
+	ps := pathParamsFromContext(r.Context())
+	if ps == nil {
+		return nil
+	}
+	return ps.list(nil)
+}
+
+// pathParams holds the path parameters captured for a request in the order they appear in the path.
+type pathParams struct {
+	keys   []string
+	values []string
+
+	// parent holds the parameters captured by the Mux this one is mounted on.
+	parent *pathParams
+
+	// prefix is the escaped path matched before the catch-all of a mounted Mux,
+	// and rest is the request path following it, which the mounted Mux serves.
+	prefix string
+	rest   string
+}
+
+var pathParamsPool = sync.Pool{
+	New: func() interface{} {
+		return &pathParams{
+			keys:   make([]string, 0, 8),
+			values: make([]string, 0, 8),
+		}
+	},
+}
+
+func (ps *pathParams) add(k, v string) {
+	ps.keys = append(ps.keys, k)
+	ps.values = append(ps.values, v)
+}
+
+// truncate removes the parameters added after the first n.
+func (ps *pathParams) truncate(n int) {
+	ps.keys = ps.keys[:n]
+	ps.values = ps.values[:n]
+}
+
+// unescape decodes the percent-encoded values captured from an escaped path.
+// A value that can not be decoded is kept as it is.
+func (ps *pathParams) unescape() {
+	for i, v := range ps.values {
+		if strings.IndexByte(v, '%') == -1 {
+			continue
+		}
+		if u, err := url.PathUnescape(v); err == nil {
+			ps.values[i] = u
+		}
+	}
+}
+
+func (ps *pathParams) reset() {
+	ps.truncate(0)
+	ps.parent = nil
+	ps.prefix = ""
+	ps.rest = ""
+}
+
+// mountPrefix returns the prefixes of the Muxes the request was mounted on, outermost first.
+func (ps *pathParams) mountPrefix() string {
+	if ps.parent != nil {
+		return ps.parent.mountPrefix() + ps.prefix
+	}
+	return ps.prefix
+}
+
+// get returns the value of k. When k is captured more than once, the last value is returned.
+func (ps *pathParams) get(k string) (string, bool) {
+	for i := len(ps.keys) - 1; i >= 0; i-- {
+		if ps.keys[i] == k {
+			return ps.values[i], true
+		}
+	}
+
+	if ps.parent != nil {
+		return ps.parent.get(k)
+	}
+	return "", false
+}
+
+func (ps *pathParams) list(params []Param) []Param {
+	if ps.parent != nil {
+		params = ps.parent.list(params)
+	}
+	for i, k := range ps.keys {
+		params = append(params, Param{Key: k, Value: ps.values[i]})
+	}
+	return params
+}
+
+func pathParamsFromContext(ctx context.Context) *pathParams {
+	ps, _ := ctx.Value(pathParamsKey).(*pathParams)
+	return ps
+}
+
+// withPathParams attaches ps to r. Parameters attached by an outer Mux stay readable.
+func withPathParams(r *http.Request, ps *pathParams) *http.Request {
+	ps.parent = pathParamsFromContext(r.Context())
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey, ps))
+}
@@ -0,0 +1,54 @@
+package eagle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	sub := NewRouter()
+	var got []Param
+	var catchAll string
+	err := sub.HandleFunc(http.MethodGet, "/posts/{postID:int}", func(w http.ResponseWriter, r *http.Request) {
+		got = Params(r)
+		catchAll = PathParam(r, "*")
+	})
+	if err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	router := NewRouter()
+	if err := router.Mount("/users/{userID:int}", sub); err != nil {
+		t.Fatalf("Mount failed err: %s", err)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1/posts/2", nil))
+
+	want := []Param{
+		{Key: "userID", Value: "1"},
+		{Key: "postID", Value: "2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Params result: %+v, expected: %+v", got, want)
+	}
+
+	if catchAll != "" {
+		t.Errorf("PathParam result: %s, expected: empty", catchAll)
+	}
+}
+
+func TestParamsContextKeyCollision(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "EaglePathParams", "other"))
+
+	if ps := Params(r); ps != nil {
+		t.Errorf("Params result: %+v, expected: nil", ps)
+	}
+
+	if v := PathParam(r, "id"); v != "" {
+		t.Errorf("PathParam result: %s, expected: empty", v)
+	}
+}
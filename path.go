@@ -0,0 +1,119 @@
+package eagle
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// CleanPathPolicy decides how a request path containing `//`, `.` or `..` segments is matched.
+type CleanPathPolicy int
+
+const (
+	// CleanPathNone matches the request path as it is.
+	CleanPathNone CleanPathPolicy = iota
+
+	// CleanPathMatch matches the cleaned request path, so "//things" and "/things/../things" reach "/things".
+	CleanPathMatch
+
+	// CleanPathRedirect redirects a request to the cleaned path.
+	// GET and HEAD are redirected with 301 and other methods with 308.
+	CleanPathRedirect
+)
+
+// WithCleanPath sets the path cleaning policy of the Mux. The default is CleanPathNone.
+func WithCleanPath(policy CleanPathPolicy) MuxOption {
+	return func(mux *Mux) {
+		mux.cleanPath = policy
+	}
+}
+
+// WithRawPath makes the Mux match the escaped request path, so that a path parameter can contain an encoded `/`.
+// PathParam returns the unescaped value. Static parts of patterns must be written in their escaped form.
+func WithRawPath() MuxOption {
+	return func(mux *Mux) {
+		mux.rawPath = true
+	}
+}
+
+// cleanPath returns p with `//`, `.` and `..` segments resolved like path.Clean, keeping a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	if p[0] == '/' && !strings.Contains(p, "//") && !strings.Contains(p, "/.") {
+		return p
+	}
+
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	c := path.Clean(p)
+	if strings.HasSuffix(p, "/") && c != "/" {
+		c += "/"
+	}
+	return c
+}
+
+// toggleTrailingSlash adds a trailing slash to p or removes it.
+func toggleTrailingSlash(p string) string {
+	if len(p) > 1 && strings.HasSuffix(p, "/") {
+		return strings.TrimRight(p, "/")
+	}
+	return p + "/"
+}
+
+// redirectTrailingSlash redirects to the matched path with or without the trailing slash.
+func (mux *Mux) redirectTrailingSlash(w http.ResponseWriter, r *http.Request) {
+	mux.redirect(w, r, toggleTrailingSlash)
+}
+
+// redirectCleanPath redirects to the cleaned request path.
+func (mux *Mux) redirectCleanPath(w http.ResponseWriter, r *http.Request) {
+	mux.redirect(w, r, func(p string) string { return p })
+}
+
+// redirect redirects to the path matched by the Mux converted by convert.
+// The location is built from the cleaned path rather than from the request, so that it always
+// stays on this host, and the prefixes of the Muxes r was mounted on are kept.
+func (mux *Mux) redirect(w http.ResponseWriter, r *http.Request, convert func(string) string) {
+	p := r.URL.Path
+	if mux.rawPath {
+		p = r.URL.EscapedPath()
+	}
+	if mux.cleanPath != CleanPathNone {
+		p = cleanPath(p)
+	}
+	if !mux.rawPath {
+		p = escapePath(p)
+	}
+
+	location := convert(p)
+	if ps := pathParamsFromContext(r.Context()); ps != nil {
+		location = ps.mountPrefix() + location
+	}
+
+	// A location starting with // is a reference to another host.
+	if strings.HasPrefix(location, "//") {
+		location = "/" + strings.TrimLeft(location, "/")
+	}
+
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, location, code)
+}
+
+// escapePath returns the escaped form of the unescaped path p.
+func escapePath(p string) string {
+	u := url.URL{Path: p}
+	return u.EscapedPath()
+}
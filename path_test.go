@@ -0,0 +1,242 @@
+package eagle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: "/"},
+		{path: "/", want: "/"},
+		{path: "/things", want: "/things"},
+		{path: "//things", want: "/things"},
+		{path: "/things/../things", want: "/things"},
+		{path: "/things/./", want: "/things/"},
+		{path: "/a/b/../../..", want: "/"},
+		{path: "things", want: "/things"},
+		{path: "/.well-known", want: "/.well-known"},
+	}
+
+	for _, td := range tests {
+		t.Run(td.path, func(t *testing.T) {
+			if got := cleanPath(td.path); got != td.want {
+				t.Errorf("cleanPath result: %s, expected: %s", got, td.want)
+			}
+		})
+	}
+}
+
+func TestMuxCleanPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   CleanPathPolicy
+		target   string
+		code     int
+		location string
+	}{
+		{
+			name:   "Path is not cleaned by default",
+			policy: CleanPathNone,
+			target: "//things",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:   "Double slash is cleaned",
+			policy: CleanPathMatch,
+			target: "//things",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "Dot dot segment is cleaned",
+			policy: CleanPathMatch,
+			target: "/users/../things",
+			code:   http.StatusOK,
+		},
+		{
+			name:     "Request is redirected to the cleaned path",
+			policy:   CleanPathRedirect,
+			target:   "/users/../things?page=2",
+			code:     http.StatusMovedPermanently,
+			location: "/things?page=2",
+		},
+		{
+			name:   "Clean path is not redirected",
+			policy: CleanPathRedirect,
+			target: "/things",
+			code:   http.StatusOK,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(WithCleanPath(td.policy))
+			if err := router.SetResource("/things", &namedResource{}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.target, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if location := w.Header().Get("Location"); location != td.location {
+				t.Errorf("Location header result: %s, expected: %s", location, td.location)
+			}
+		})
+	}
+}
+
+func TestMuxRawPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []MuxOption
+		mount   string
+		target  string
+		code    int
+		body    string
+	}{
+		{
+			name:   "Encoded slash is decoded before matching by default",
+			target: "/files/a%2Fb",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:    "Encoded slash can be matched by a parameter on the raw path",
+			options: []MuxOption{WithRawPath()},
+			target:  "/files/a%2Fb",
+			code:    http.StatusOK,
+			body:    "a/b",
+		},
+		{
+			name:    "Encoded characters are unescaped",
+			options: []MuxOption{WithRawPath()},
+			target:  "/files/my%20file",
+			code:    http.StatusOK,
+			body:    "my file",
+		},
+		{
+			name:    "Encoded slash is kept through Mount",
+			options: []MuxOption{WithRawPath()},
+			mount:   "/m",
+			target:  "/m/files/a%2Fb",
+			code:    http.StatusOK,
+			body:    "a/b",
+		},
+		{
+			name:    "Encoded characters are unescaped through Mount",
+			options: []MuxOption{WithRawPath()},
+			mount:   "/m",
+			target:  "/m/files/my%20file",
+			code:    http.StatusOK,
+			body:    "my file",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(td.options...)
+			err := router.HandleFunc(http.MethodGet, "/files/{name}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(PathParam(r, "name")))
+			})
+			if err != nil {
+				t.Fatalf("HandleFunc failed err: %s", err)
+			}
+
+			var h http.Handler = router
+			if td.mount != "" {
+				parent := NewRouter(td.options...)
+				if err := parent.Mount(td.mount, router); err != nil {
+					t.Fatalf("Mount failed err: %s", err)
+				}
+				h = parent
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.target, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if w.Body.String() != td.body {
+				t.Errorf("body result: %s, expected: %s", w.Body.String(), td.body)
+			}
+		})
+	}
+}
+
+func TestMuxRedirectLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []MuxOption
+		mount    string
+		target   string
+		code     int
+		location string
+	}{
+		{
+			name:     "Trailing slash redirect is built from the cleaned path",
+			opts:     []MuxOption{WithCleanPath(CleanPathMatch), WithTrailingSlash(TrailingSlashRedirect)},
+			target:   "//evil.com/../users",
+			code:     http.StatusMovedPermanently,
+			location: "/users/",
+		},
+		{
+			name:     "Clean path redirect never leaves the host",
+			opts:     []MuxOption{WithCleanPath(CleanPathRedirect)},
+			target:   "//evil.com/../users/",
+			code:     http.StatusMovedPermanently,
+			location: "/users/",
+		},
+		{
+			name:     "Escaped characters are kept escaped",
+			opts:     []MuxOption{WithTrailingSlash(TrailingSlashRedirect)},
+			target:   "/files/a%20b",
+			code:     http.StatusMovedPermanently,
+			location: "/files/a%20b/",
+		},
+		{
+			name:     "Prefix of the mounting Mux is kept",
+			opts:     []MuxOption{WithCleanPath(CleanPathMatch), WithTrailingSlash(TrailingSlashRedirect)},
+			mount:    "/api",
+			target:   "/api//evil.com/../users?page=2",
+			code:     http.StatusMovedPermanently,
+			location: "/api/users/?page=2",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(td.opts...)
+			for _, p := range []string{"/users/", "/files/{name}/"} {
+				if err := router.SetResource(p, &namedResource{}); err != nil {
+					t.Fatalf("SetResource failed err: %s", err)
+				}
+			}
+
+			var h http.Handler = router
+			if td.mount != "" {
+				parent := NewRouter()
+				if err := parent.Mount(td.mount, router); err != nil {
+					t.Fatalf("Mount failed err: %s", err)
+				}
+				h = parent
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, td.target, nil))
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if location := w.Header().Get("Location"); location != td.location {
+				t.Errorf("Location header result: %s, expected: %s", location, td.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package eagle
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is a problem detail of RFC 7807, rendered by RenderProblem as application/problem+json.
+type Problem struct {
+	// Type is a URI identifying the problem type. Empty means "about:blank".
+	Type string
+
+	// Title is a short summary of the problem type.
+	Title string
+
+	// Status is the status code of the response. 0 means 500 Internal Server Error.
+	Status int
+
+	// Detail explains this occurrence of the problem.
+	Detail string
+
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string
+
+	// Extensions are additional members. They do not override the members above.
+	Extensions map[string]interface{}
+}
+
+// NewProblem returns a Problem titled by the status text of status.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON encodes p as a JSON object with the extensions as top-level members.
+// Empty members are omitted.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	members := map[string]string{
+		"type":     p.Type,
+		"title":    p.Title,
+		"detail":   p.Detail,
+		"instance": p.Instance,
+	}
+	for k, v := range members {
+		if v != "" {
+			m[k] = v
+		} else {
+			delete(m, k)
+		}
+	}
+
+	if p.Status != 0 {
+		m["status"] = p.Status
+	} else {
+		delete(m, "status")
+	}
+	return json.Marshal(m)
+}
+
+// RenderProblem writes p as application/problem+json with the status of p.
+func RenderProblem(w http.ResponseWriter, p *Problem) error {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	return enc.Encode(p)
+}
+
+// asProblem returns the first Problem in the chain of errors wrapped by err.
+func asProblem(err error) (*Problem, bool) {
+	for err != nil {
+		if p, ok := err.(*Problem); ok {
+			return p, true
+		}
+		err = unwrapError(err)
+	}
+	return nil, false
+}
+
+// WithProblemDetails makes the default responses of the Mux application/problem+json:
+// the 404 and 405 responses and the responses of the default error handler,
+// which renders an HTTPError as a Problem with its Code as the "code" member.
+func WithProblemDetails() MuxOption {
+	return func(mux *Mux) {
+		mux.problemDetails = true
+	}
+}
+
+func handleNotFoundProblem(w http.ResponseWriter, r *http.Request) {
+	RenderProblem(w, NewProblem(http.StatusNotFound, ""))
+}
+
+func handleMethodNotAllowedProblem(w http.ResponseWriter, r *http.Request) {
+	RenderProblem(w, NewProblem(http.StatusMethodNotAllowed, ""))
+}
+
+func handleErrorProblem(w http.ResponseWriter, r *http.Request, err error) {
+	if p, ok := asProblem(err); ok {
+		RenderProblem(w, p)
+		return
+	}
+
+	he, ok := asHTTPError(err)
+	if !ok {
+		he = NewHTTPError(http.StatusInternalServerError, "", "")
+	}
+
+	status := he.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	p := NewProblem(status, he.Message)
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+	if he.Code != "" {
+		p.Extensions = map[string]interface{}{"code": he.Code}
+	}
+	RenderProblem(w, p)
+}
@@ -0,0 +1,264 @@
+package eagle
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    string
+	}{
+		{
+			name:    "Empty members are omitted",
+			problem: Problem{Title: "Not Found", Status: http.StatusNotFound},
+			want:    `{"status":404,"title":"Not Found"}`,
+		},
+		{
+			name: "All members",
+			problem: Problem{
+				Type:     "https://example.com/probs/out-of-credit",
+				Title:    "You do not have enough credit.",
+				Status:   http.StatusForbidden,
+				Detail:   "Your current balance is 30, but that costs 50.",
+				Instance: "/account/12345/msgs/abc",
+			},
+			want: `{"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`,
+		},
+		{
+			name: "Extensions do not override members",
+			problem: Problem{
+				Title:      "Bad Request",
+				Status:     http.StatusBadRequest,
+				Extensions: map[string]interface{}{"balance": 30, "title": "overridden", "status": 200},
+			},
+			want: `{"balance":30,"status":400,"title":"Bad Request"}`,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			b, err := json.Marshal(td.problem)
+			if err != nil {
+				t.Fatalf("Marshal failed err: %s", err)
+			}
+
+			if string(b) != td.want {
+				t.Errorf("Marshal result: %s, expected: %s", b, td.want)
+			}
+		})
+	}
+}
+
+func TestRenderProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := RenderProblem(w, &Problem{Title: "Failed"}); err != nil {
+		t.Fatalf("RenderProblem failed err: %s", err)
+	}
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type result: %s, expected: %s", ct, "application/problem+json")
+	}
+
+	if want := `{"title":"Failed"}` + "\n"; w.Body.String() != want {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), want)
+	}
+}
+
+type bindResource struct{}
+
+func (br *bindResource) Post(w http.ResponseWriter, r *http.Request) error {
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := BindHTTP(r, &v); err != nil {
+		return err
+	}
+	w.Write([]byte(v.Name))
+	return nil
+}
+
+func TestMuxProblemDetails(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []MuxOption
+		method      string
+		path        string
+		contentType string
+		body        string
+		code        int
+		wantType    string
+		want        string
+	}{
+		{
+			name:     "Not found",
+			opts:     []MuxOption{WithProblemDetails()},
+			method:   http.MethodGet,
+			path:     "/missing",
+			code:     http.StatusNotFound,
+			wantType: "application/problem+json",
+			want:     `{"status":404,"title":"Not Found"}`,
+		},
+		{
+			name:     "Method not allowed",
+			opts:     []MuxOption{WithProblemDetails()},
+			method:   http.MethodDelete,
+			path:     "/things",
+			code:     http.StatusMethodNotAllowed,
+			wantType: "application/problem+json",
+			want:     `{"status":405,"title":"Method Not Allowed"}`,
+		},
+		{
+			name:        "Unsupported media type from Bind",
+			opts:        []MuxOption{WithProblemDetails()},
+			method:      http.MethodPost,
+			path:        "/things",
+			contentType: "text/plain",
+			body:        "name",
+			code:        http.StatusUnsupportedMediaType,
+			wantType:    "application/problem+json",
+			want:        `{"code":"unsupported_media_type","detail":"unsupported media type","status":415,"title":"Unsupported Media Type"}`,
+		},
+		{
+			name:        "Invalid body from Bind",
+			opts:        []MuxOption{WithProblemDetails()},
+			method:      http.MethodPost,
+			path:        "/things",
+			contentType: "application/json",
+			body:        "{",
+			code:        http.StatusBadRequest,
+			wantType:    "application/problem+json",
+			want:        `{"code":"invalid_request_body","detail":"invalid request body","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:        "Bind failure without problem details",
+			method:      http.MethodPost,
+			path:        "/things",
+			contentType: "application/json",
+			body:        "{",
+			code:        http.StatusBadRequest,
+			wantType:    "application/json; charset=utf-8",
+			want:        `{"code":"invalid_request_body","message":"invalid request body"}`,
+		},
+		{
+			name:        "Bound request",
+			opts:        []MuxOption{WithProblemDetails()},
+			method:      http.MethodPost,
+			path:        "/things",
+			contentType: "application/json",
+			body:        `{"name":"thing"}`,
+			code:        http.StatusOK,
+			wantType:    "text/plain; charset=utf-8",
+			want:        "thing",
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			router := NewRouter(td.opts...)
+			if err := router.SetResource("/things", &bindResource{}); err != nil {
+				t.Fatalf("SetResource failed err: %s", err)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(td.method, td.path, strings.NewReader(td.body))
+			r.Header.Set("Content-Type", td.contentType)
+			router.ServeHTTP(w, r)
+			if w.Code != td.code {
+				t.Errorf("status code result: %d, expected: %d", w.Code, td.code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != td.wantType {
+				t.Errorf("Content-Type result: %s, expected: %s", ct, td.wantType)
+			}
+
+			if got := strings.TrimSuffix(w.Body.String(), "\n"); got != td.want {
+				t.Errorf("body result: %s, expected: %s", got, td.want)
+			}
+		})
+	}
+}
+
+func TestMuxReturnedProblem(t *testing.T) {
+	p := NewProblem(http.StatusConflict, "thing already exists")
+	router := NewRouter()
+	if err := router.SetResource("/things", &errorResource{err: &wrappedError{err: p}}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusConflict)
+	}
+
+	want := `{"detail":"thing already exists","status":409,"title":"Conflict"}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), want)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+		status      int
+	}{
+		{
+			name:        "Empty body",
+			contentType: "application/json",
+			err:         io.EOF,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "Unsupported media type",
+			contentType: "text/plain",
+			body:        "name",
+			err:         ErrUnsupportedMediaType,
+			status:      http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(td.body))
+			r.Header.Set("Content-Type", td.contentType)
+			if err := Bind(r, &struct{}{}); err != td.err {
+				t.Errorf("Bind error result: %v, expected: %v", err, td.err)
+			}
+
+			r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(td.body))
+			r.Header.Set("Content-Type", td.contentType)
+			he, ok := BindHTTP(r, &struct{}{}).(*HTTPError)
+			if !ok {
+				t.Fatal("BindHTTP error must be an *HTTPError")
+			}
+			if he.Status != td.status {
+				t.Errorf("status result: %d, expected: %d", he.Status, td.status)
+			}
+			if he.Err != td.err {
+				t.Errorf("BindHTTP error result: %v, expected: %v", he.Err, td.err)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{]"))
+	r.Header.Set("Content-Type", "application/json")
+	if err := Bind(r, &struct{}{}); err == nil {
+		t.Fatal("Bind must return an error for invalid JSON")
+	} else if _, ok := err.(*json.SyntaxError); !ok {
+		t.Errorf("Bind error result: %T, expected: *json.SyntaxError", err)
+	}
+}
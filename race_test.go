@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package eagle
+
+// raceEnabled reports whether the race detector is enabled.
+// sync.Pool drops items at random under the race detector, so allocations cannot be counted.
+const raceEnabled = true
@@ -0,0 +1,63 @@
+package eagle
+
+import (
+	"regexp"
+)
+
+// RouteInfo describes a route registered on a Mux.
+type RouteInfo struct {
+	Pattern string
+	Name    string
+	Regexp  *regexp.Regexp
+
+	// Methods are the methods the route serves. It is empty when the route serves every method.
+	Methods []string
+
+	// Middlewares is the number of middleware that run for every method of the route,
+	// including the middleware added to the Mux by Use.
+	Middlewares int
+
+	// Resource is the registered Resource, http.Handler or mounted *Mux. It is nil for HandleFunc.
+	Resource interface{}
+}
+
+// Routes returns the routes registered on mux in registration order.
+// Routes of a mounted Mux are not included; they can be listed from RouteInfo.Resource.
+func (mux *Mux) Routes() []RouteInfo {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	t := mux.table()
+	routes := make([]RouteInfo, 0, len(t.routes))
+	for _, ri := range t.routes {
+		routes = append(routes, mux.routeInfo(ri))
+	}
+	return routes
+}
+
+// Walk calls fn for each route registered on mux in registration order.
+// Walk stops and returns the error when fn returns an error.
+func (mux *Mux) Walk(fn func(route RouteInfo) error) error {
+	for _, route := range mux.Routes() {
+		if err := fn(route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mux *Mux) routeInfo(ri *resourceInfo) RouteInfo {
+	var allowed []string
+	if ri.handler == nil {
+		allowed = allowedMethods(ri.handlers)
+	}
+
+	return RouteInfo{
+		Pattern:     ri.pattern,
+		Name:        ri.name,
+		Regexp:      ri.re,
+		Methods:     allowed,
+		Middlewares: len(mux.middlewares) + len(ri.group.chain()) + len(ri.middlewares),
+		Resource:    ri.resource,
+	}
+}
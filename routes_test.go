@@ -0,0 +1,95 @@
+package eagle
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMuxRoutes(t *testing.T) {
+	router := NewRouter()
+	router.Use(recordMiddleware("global"))
+
+	things := &writableResource{}
+	if err := router.SetResource("/things/", things, WithName("things"), WithMiddleware(recordMiddleware("resource"))); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	admin := router.Group("/admin", recordMiddleware("admin"))
+	if err := admin.HandleFunc(http.MethodPut, "/users/{id:int}", func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+		t.Fatalf("HandleFunc failed err: %s", err)
+	}
+
+	sub := NewRouter()
+	if err := router.Mount("/api", sub); err != nil {
+		t.Fatalf("Mount failed err: %s", err)
+	}
+
+	type want struct {
+		Pattern     string
+		Name        string
+		Regexp      string
+		Methods     []string
+		Middlewares int
+		Resource    interface{}
+	}
+	wants := []want{
+		{
+			Pattern:     "/things",
+			Name:        "things",
+			Regexp:      "^/things$",
+			Methods:     []string{"GET", "HEAD", "POST", "DELETE", "OPTIONS"},
+			Middlewares: 2,
+			Resource:    things,
+		},
+		{
+			Pattern:     "/admin/users/{id:int}",
+			Regexp:      "^/admin/users/(?P<id>[0-9]+)$",
+			Methods:     []string{"PUT", "OPTIONS"},
+			Middlewares: 2,
+		},
+		{
+			Pattern:     "/api/*",
+			Regexp:      "^/api(?:/(.*))?$",
+			Middlewares: 1,
+			Resource:    sub,
+		},
+	}
+
+	routes := router.Routes()
+	if len(routes) != len(wants) {
+		t.Fatalf("Routes result: %+v, expected: %+v", routes, wants)
+	}
+
+	for i, route := range routes {
+		got := want{
+			Pattern:     route.Pattern,
+			Name:        route.Name,
+			Regexp:      route.Regexp.String(),
+			Methods:     route.Methods,
+			Middlewares: route.Middlewares,
+			Resource:    route.Resource,
+		}
+		if !reflect.DeepEqual(got, wants[i]) {
+			t.Errorf("Routes result: %+v, expected: %+v", got, wants[i])
+		}
+	}
+
+	var patterns []string
+	errStop := errors.New("stop")
+	err := router.Walk(func(route RouteInfo) error {
+		patterns = append(patterns, route.Pattern)
+		if len(patterns) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Walk result: %s, expected: %s", err, errStop)
+	}
+
+	if !reflect.DeepEqual(patterns, []string{"/things", "/admin/users/{id:int}"}) {
+		t.Errorf("Walk result: %v", patterns)
+	}
+}
@@ -0,0 +1,150 @@
+package eagle
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routingTable holds everything the Mux reads to serve a request.
+// A routingTable is never changed once stored in the Mux. Registration builds a new one and swaps it in,
+// so requests being served keep using the table, resources and middleware they started with.
+type routingTable struct {
+	tree    *node
+	routes  []*resourceInfo
+	hosts   []*hostRoute
+	schemes []*schemeRoute
+
+	// The handlers below have the middleware added by Use applied.
+	notFound      http.HandlerFunc
+	cleanPath     http.HandlerFunc
+	trailingSlash http.HandlerFunc
+
+	errorHandler ErrorHandlerFunc
+}
+
+// table returns the routingTable currently used to serve requests.
+func (mux *Mux) table() *routingTable {
+	return mux.current.Load().(*routingTable)
+}
+
+// findRoute returns the resourceInfo registered under the normalized pattern.
+func (t *routingTable) findRoute(pattern string) *resourceInfo {
+	for _, ri := range t.routes {
+		if ri.pattern == pattern {
+			return ri
+		}
+	}
+	return nil
+}
+
+// insertTree adds ri to a copy of the tree of t and replaces the tree with it.
+func (mux *Mux) insertTree(t *routingTable, ri *resourceInfo) error {
+	tree := t.tree.clone()
+	if err := tree.insert(ri.pattern, ri); err != nil {
+		return err
+	}
+
+	// A catch-all also serves the path without it unless another resource is registered there.
+	if i := catchAllIndex(ri.pattern); i != -1 && mux.trailingSlash == TrailingSlashLenient {
+		prefix := strings.TrimRight(ri.pattern[:i], "/")
+		if t.findRoute(prefix) == nil {
+			if err := tree.insert(prefix, ri); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.tree = tree
+	return nil
+}
+
+// compose rebuilds the routingTable after the middleware or the error handlers change,
+// so that no middleware is applied while serving a request. The caller must hold mux.mu.
+func (mux *Mux) compose() {
+	mux.rebuild(mux.table().routes)
+}
+
+// rebuild replaces the routingTable with one serving routes. The caller must hold mux.mu.
+func (mux *Mux) rebuild(routes []*resourceInfo) {
+	old := mux.table()
+	t := &routingTable{
+		tree:          &node{},
+		routes:        make([]*resourceInfo, 0, len(routes)),
+		hosts:         make([]*hostRoute, 0, len(old.hosts)),
+		schemes:       make([]*schemeRoute, 0, len(old.schemes)),
+		cleanPath:     mux.wrap(mux.redirectCleanPath),
+		trailingSlash: mux.wrap(mux.redirectTrailingSlash),
+	}
+
+	notFound := mux.notFound
+	if notFound == nil {
+		notFound = handleNotFound
+		if mux.problemDetails {
+			notFound = handleNotFoundProblem
+		}
+	}
+	t.notFound = mux.wrap(notFound)
+
+	t.errorHandler = mux.errorHandler
+	if t.errorHandler == nil {
+		t.errorHandler = handleError
+		if mux.problemDetails {
+			t.errorHandler = handleErrorProblem
+		}
+	}
+
+	for _, hr := range old.hosts {
+		c := *hr
+		c.handler = mux.wrap(c.mux.ServeHTTP)
+		t.hosts = append(t.hosts, &c)
+	}
+	for _, sr := range old.schemes {
+		c := *sr
+		c.handler = mux.wrap(c.mux.ServeHTTP)
+		t.schemes = append(t.schemes, &c)
+	}
+
+	for _, ri := range routes {
+		c := *ri
+		mux.composeRoute(&c)
+		// The patterns were inserted before, so they are valid.
+		mux.insertTree(t, &c)
+		t.routes = append(t.routes, &c)
+	}
+
+	mux.current.Store(t)
+}
+
+// composeRoute applies the middleware of ri, its group and the Mux to the handlers of ri.
+func (mux *Mux) composeRoute(ri *resourceInfo) {
+	mw := append(ri.group.chain(), ri.middlewares...)
+	chain := func(h http.HandlerFunc) http.HandlerFunc {
+		if len(mw) > 0 {
+			h = ChainMiddleware(mw...)(h)
+		}
+		return mux.wrap(h)
+	}
+
+	ri.chains = make(map[string]http.HandlerFunc, len(ri.handlers))
+	for method, h := range ri.handlers {
+		ri.chains[method] = chain(h)
+	}
+
+	if ri.handler != nil {
+		ri.fallback = chain(ri.handler)
+		return
+	}
+
+	allow := ri.allow
+	methodNotAllowed := mux.methodNotAllowed
+	if methodNotAllowed == nil {
+		methodNotAllowed = handleMethodNotAllowed
+		if mux.problemDetails {
+			methodNotAllowed = handleMethodNotAllowedProblem
+		}
+	}
+	ri.fallback = chain(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		methodNotAllowed(w, r)
+	})
+}
@@ -0,0 +1,149 @@
+package eagle
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestNodeInsertCopiesNodes(t *testing.T) {
+	tree := &node{}
+	for _, p := range []string{"/users", "/users/{id:int}", "/files/*"} {
+		if err := tree.insert(p, &resourceInfo{pattern: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []string{
+		"/users",
+		"/users/{id:int}",
+		"/users/{id:int}/posts",
+		"/uploads",
+		"/files/*",
+		"/files",
+	}
+
+	for _, pattern := range tests {
+		t.Run(pattern, func(t *testing.T) {
+			c := tree.clone()
+			if err := c.insert(pattern, &resourceInfo{pattern: "new"}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"/users", "/users/1", "/users/1/posts", "/uploads", "/files/a", "/files"} {
+				if ri := tree.find(path, &pathParams{}); ri != nil && ri.pattern == "new" {
+					t.Errorf("original tree serves the inserted resource path: %s", path)
+				}
+			}
+
+			if ri := c.find("/users", &pathParams{}); ri == nil {
+				t.Error("copied tree lost /users")
+			}
+		})
+	}
+}
+
+func TestMuxConcurrentRegistration(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/things", &namedResource{name: "things"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if err := router.SetResource(fmt.Sprintf("/things%d/{id}", i), &namedResource{}); err != nil {
+				t.Errorf("SetResource failed err: %s", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			pattern := fmt.Sprintf("/funcs%d", i)
+			if err := router.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+				t.Errorf("HandleFunc failed err: %s", err)
+			}
+			if err := router.HandleFunc(http.MethodPost, pattern, func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+				t.Errorf("HandleFunc failed err: %s", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			router.Use(passMiddleware)
+			router.Routes()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things", nil))
+			if w.Code != http.StatusOK || w.Body.String() != "things" {
+				t.Errorf("response result: %d %s, expected: %d %s", w.Code, w.Body.String(), http.StatusOK, "things")
+			}
+		}
+	}()
+	wg.Wait()
+
+	if got := len(router.Routes()); got != 2*n+1 {
+		t.Errorf("routes result: %d, expected: %d", got, 2*n+1)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/funcs0", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code result: %d, expected: %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestMuxFailedRegistrationKeepsRoutes(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/users/{id:int}", &namedResource{name: "user"}); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+	if err := router.SetResource("/users/{id:[0-9+}/posts", &namedResource{}); err == nil {
+		t.Fatal("SetResource must return an error for an invalid regular expression")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Body.String() != "user" {
+		t.Errorf("body result: %s, expected: %s", w.Body.String(), "user")
+	}
+	if got := len(router.Routes()); got != 1 {
+		t.Errorf("routes result: %d, expected: %d", got, 1)
+	}
+}
+
+func TestSpansSegment(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{pattern: "/users/{id:[0-9]+}", want: false},
+		{pattern: "/users/{id}", want: false},
+		{pattern: "/users/{id:int}.json", want: false},
+		{pattern: "/files/{p:.+}", want: true},
+		{pattern: "/files/{p:[a-z/]+}/raw", want: true},
+		{pattern: "/r/{n:[a-z.]+}.json", want: true},
+		{pattern: "/r/{n:(?i)X+}x", want: true},
+		{pattern: "/posts/{a:slug}-{b:int}", want: true},
+		{pattern: "/files/*path", want: false},
+	}
+
+	for _, td := range tests {
+		t.Run(td.pattern, func(t *testing.T) {
+			if got := spansSegment(td.pattern); got != td.want {
+				t.Errorf("spansSegment result: %v, expected: %v", got, td.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package eagle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+type nodeType uint8
+
+const (
+	ntStatic   nodeType = iota // /users
+	ntParam                    // /{id:[0-9]+}
+	ntCatchAll                 // /*path
+)
+
+type nodes []*node
+
+// node is a node of the compressed prefix tree used to look up resources.
+// Static nodes hold a literal prefix shared by all of their descendants,
+// param nodes match a single path segment, or more when their regular expression spans it,
+// and catch-all nodes match the rest of the path.
+type node struct {
+	typ nodeType
+
+	// prefix is the literal text for static nodes and the raw segment
+	// (e.g. `{id:[0-9]+}` or `*path`) for param and catch-all nodes.
+	prefix string
+
+	// label is the first byte of prefix, used to pick static children.
+	label byte
+
+	// tail is the byte that terminates a param value.
+	tail byte
+
+	// key is the name of the path parameter captured by this node.
+	key string
+
+	// rex validates the value of a param node. A nil rex accepts any segment.
+	rex *regexp.Regexp
+
+	// spans is set when rex can match `/` or tail, so the value is not cut at the first of them.
+	spans bool
+
+	children [ntCatchAll + 1]nodes
+
+	ri *resourceInfo
+}
+
+// nextSegment returns the type, the raw text, the parameter name and the regular expression
+// of the segment at the beginning of pattern.
+func nextSegment(pattern string) (nodeType, string, string, string, error) {
+	ps := strings.IndexAny(pattern, "{*")
+	if ps != 0 {
+		if ps == -1 {
+			ps = len(pattern)
+		}
+		return ntStatic, pattern[:ps], "", "", nil
+	}
+
+	if pattern[0] == '*' {
+		key := pattern[1:]
+		if key == "" {
+			key = "*"
+		}
+		return ntCatchAll, pattern, key, "", nil
+	}
+
+	pe := strings.Index(pattern, "}")
+	if pe == -1 {
+		return ntStatic, pattern, "", "", errors.New("The path parameter's } is not set")
+	}
+
+	key, expr := splitParam(pattern[1:pe])
+	return ntParam, pattern[:pe+1], key, expr, nil
+}
+
+// spansSegment reports whether a parameter of pattern can match `/` or the byte following it.
+func spansSegment(pattern string) bool {
+	search := pattern
+	for len(search) > 0 {
+		typ, seg, _, expr, err := nextSegment(search)
+		if err != nil {
+			return false
+		}
+		search = search[len(seg):]
+		if typ != ntParam {
+			continue
+		}
+
+		tail := byte('/')
+		if len(search) > 0 {
+			tail = search[0]
+		}
+		if paramSpans(expr, tail) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramSpans reports whether the regular expression expr of a parameter can match `/` or tail.
+func paramSpans(expr string, tail byte) bool {
+	if expr == "" {
+		return false
+	}
+	re, err := syntax.Parse(paramExpr(expr), syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return canMatchByte(re, '/') || canMatchByte(re, tail)
+}
+
+// canMatchByte reports whether a string matched by re can contain c.
+func canMatchByte(re *syntax.Regexp, c byte) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if r == rune(c) || (re.Flags&syntax.FoldCase != 0 && strings.EqualFold(string(r), string(c))) {
+				return true
+			}
+		}
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] <= rune(c) && rune(c) <= re.Rune[i+1] {
+				return true
+			}
+		}
+	case syntax.OpAnyChar:
+		return true
+	case syntax.OpAnyCharNotNL:
+		return c != '\n'
+	}
+
+	for _, sub := range re.Sub {
+		if canMatchByte(sub, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func longestPrefix(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+
+	i := 0
+	for ; i < max; i++ {
+		if a[i] != b[i] {
+			break
+		}
+	}
+	return i
+}
+
+func (ns nodes) findEdge(label byte) *node {
+	for _, n := range ns {
+		if n.label == label {
+			return n
+		}
+	}
+	return nil
+}
+
+func (ns nodes) findPrefix(prefix string) *node {
+	for _, n := range ns {
+		if n.prefix == prefix {
+			return n
+		}
+	}
+	return nil
+}
+
+func (ns nodes) findParam(prefix string, tail byte) *node {
+	for _, n := range ns {
+		if n.prefix == prefix && n.tail == tail {
+			return n
+		}
+	}
+	return nil
+}
+
+// clone returns a copy of n that can be changed without changing n.
+// The children themselves are shared.
+func (n *node) clone() *node {
+	c := *n
+	for i, ns := range n.children {
+		c.children[i] = append(nodes(nil), ns...)
+	}
+	return &c
+}
+
+func (n *node) replaceChild(old, child *node) {
+	for i, c := range n.children[old.typ] {
+		if c == old {
+			n.children[old.typ][i] = child
+			return
+		}
+	}
+}
+
+// copyChild replaces child of n with a clone so that it can be changed, and returns the clone.
+func (n *node) copyChild(child *node) *node {
+	c := child.clone()
+	n.replaceChild(child, c)
+	return c
+}
+
+// addParamChild adds child to the param children of n.
+// Params followed by a literal are tried before params that span the whole segment,
+// otherwise params are tried in registration order.
+func (n *node) addParamChild(child *node) {
+	ns := n.children[ntParam]
+	i := len(ns)
+	if child.tail != '/' {
+		for j, c := range ns {
+			if c.tail == '/' {
+				i = j
+				break
+			}
+		}
+	}
+
+	ns = append(ns, nil)
+	copy(ns[i+1:], ns[i:])
+	ns[i] = child
+	n.children[ntParam] = ns
+}
+
+// insert adds ri to the tree under pattern.
+// The descendants of n are copied before they are changed, so a tree sharing them with n is left as it is.
+func (n *node) insert(pattern string, ri *resourceInfo) error {
+	search := pattern
+	for len(search) > 0 {
+		typ, seg, key, expr, err := nextSegment(search)
+		if err != nil {
+			return err
+		}
+
+		switch typ {
+		case ntStatic:
+			child := n.children[ntStatic].findEdge(seg[0])
+			if child == nil {
+				child = &node{typ: ntStatic, prefix: seg, label: seg[0]}
+				n.children[ntStatic] = append(n.children[ntStatic], child)
+				n = child
+				search = search[len(seg):]
+				continue
+			}
+
+			child = n.copyChild(child)
+			l := longestPrefix(seg, child.prefix)
+			if l < len(child.prefix) {
+				split := &node{typ: ntStatic, prefix: seg[:l], label: seg[0]}
+				n.replaceChild(child, split)
+				child.prefix = child.prefix[l:]
+				child.label = child.prefix[0]
+				split.children[ntStatic] = nodes{child}
+				child = split
+			}
+			n = child
+			search = search[l:]
+		case ntParam:
+			tail := byte('/')
+			if len(seg) < len(search) {
+				tail = search[len(seg)]
+			}
+			child := n.children[ntParam].findParam(seg, tail)
+			if child == nil {
+				child = &node{typ: ntParam, prefix: seg, key: key, tail: tail}
+				if expr != "" {
+					rex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", paramExpr(expr)))
+					if err != nil {
+						return fmt.Errorf("invalid regular expression for path parameter %s: %s", key, err)
+					}
+					child.rex = rex
+					child.spans = paramSpans(expr, tail)
+				}
+				n.addParamChild(child)
+			} else {
+				child = n.copyChild(child)
+			}
+			n = child
+			search = search[len(seg):]
+		case ntCatchAll:
+			child := n.children[ntCatchAll].findPrefix(seg)
+			if child == nil {
+				if len(n.children[ntCatchAll]) > 0 {
+					return fmt.Errorf("conflicting catch-all parameter %s", seg)
+				}
+				child = &node{typ: ntCatchAll, prefix: seg, key: key}
+				n.children[ntCatchAll] = nodes{child}
+			} else {
+				child = n.copyChild(child)
+			}
+			n = child
+			search = ""
+		}
+	}
+
+	n.ri = ri
+	return nil
+}
+
+// find returns the resourceInfo matching path and stores the captured path parameters in params.
+// Static children are tried before param children and param children before catch-all children,
+// backtracking when a branch does not lead to a resource.
+func (n *node) find(path string, params *pathParams) *resourceInfo {
+	if len(path) == 0 && n.ri != nil {
+		return n.ri
+	}
+
+	if len(path) > 0 {
+		if child := n.children[ntStatic].findEdge(path[0]); child != nil && strings.HasPrefix(path, child.prefix) {
+			if ri := child.find(path[len(child.prefix):], params); ri != nil {
+				return ri
+			}
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+		for _, child := range n.children[ntParam] {
+			if child.spans {
+				if ri := child.findSpanning(path, params); ri != nil {
+					return ri
+				}
+				continue
+			}
+
+			i := end
+			if child.tail != '/' {
+				i = strings.IndexByte(path[:end], child.tail)
+				if i == -1 {
+					continue
+				}
+			}
+
+			value := path[:i]
+			if value == "" || (child.rex != nil && !child.rex.MatchString(value)) {
+				continue
+			}
+
+			params.add(child.key, value)
+			if ri := child.find(path[i:], params); ri != nil {
+				return ri
+			}
+			params.truncate(len(params.keys) - 1)
+		}
+	}
+
+	for _, child := range n.children[ntCatchAll] {
+		if child.ri != nil {
+			params.add(child.key, path)
+			return child.ri
+		}
+	}
+
+	return nil
+}
+
+// findSpanning is find for a param node whose value can contain `/` or its tail.
+// The value ends before a tail or at the end of path, and longer values are tried first.
+func (n *node) findSpanning(path string, params *pathParams) *resourceInfo {
+	for i := len(path); i > 0; i-- {
+		if i < len(path) && path[i] != n.tail || i == len(path) && n.tail != '/' {
+			continue
+		}
+
+		value := path[:i]
+		if !n.rex.MatchString(value) {
+			continue
+		}
+
+		params.add(n.key, value)
+		if ri := n.find(path[i:], params); ri != nil {
+			return ri
+		}
+		params.truncate(len(params.keys) - 1)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package eagle
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// findRouteByName returns the resourceInfo named name.
+func (mux *Mux) findRouteByName(name string) *resourceInfo {
+	for _, ri := range mux.table().routes {
+		if ri.name == name {
+			return ri
+		}
+	}
+	return nil
+}
+
+// URL builds the path of the resource named name by WithName.
+// params are pairs of a path parameter name and its value, e.g. URL("user", "id", "1").
+// An error is returned when a value does not match the pattern of its parameter.
+func (mux *Mux) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", errors.New("params must be pairs of a key and a value")
+	}
+
+	ri := mux.findRouteByName(name)
+	if ri == nil {
+		return "", fmt.Errorf("route does not exist name: %s", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	return reversePattern(ri, values)
+}
+
+// reversePattern replaces the path parameters of the pattern of ri with values.
+func reversePattern(ri *resourceInfo, values map[string]string) (string, error) {
+	var raw, escaped strings.Builder
+	emptyCatchAll := false
+	rest := ri.pattern
+	for len(rest) > 0 {
+		typ, seg, key, _, err := nextSegment(rest)
+		if err != nil {
+			return "", err
+		}
+		rest = rest[len(seg):]
+
+		switch typ {
+		case ntStatic:
+			raw.WriteString(seg)
+			escaped.WriteString(seg)
+		case ntParam:
+			v, ok := values[key]
+			if !ok || v == "" {
+				return "", fmt.Errorf("path parameter is not set key: %s", key)
+			}
+			// A value containing `/` is rejected below unless the regular expression of key accepts it.
+			raw.WriteString(v)
+			escaped.WriteString(escapeSegments(v))
+		case ntCatchAll:
+			v := values[key]
+			emptyCatchAll = v == ""
+			raw.WriteString(v)
+			escaped.WriteString(escapeSegments(v))
+		}
+	}
+
+	match := ri.re.FindStringSubmatch(raw.String())
+	if match == nil {
+		return "", fmt.Errorf("path parameters do not match the pattern %s", ri.pattern)
+	}
+	for i, key := range ri.re.SubexpNames() {
+		if key != "" && match[i] != values[key] {
+			return "", fmt.Errorf("path parameter %s does not match the pattern %s: %s", key, ri.pattern, values[key])
+		}
+	}
+
+	u := escaped.String()
+	if emptyCatchAll {
+		u = strings.TrimRight(u, "/")
+	}
+	if u == "" {
+		return "/", nil
+	}
+	return u, nil
+}
+
+// escapeSegments escapes each part of v between slashes.
+func escapeSegments(v string) string {
+	segments := strings.Split(v, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,132 @@
+package eagle
+
+import (
+	"testing"
+)
+
+func TestMuxURL(t *testing.T) {
+	router := NewRouter()
+	resources := []struct {
+		pattern string
+		name    string
+	}{
+		{pattern: "/", name: "index"},
+		{pattern: "/users/{id:int}", name: "user"},
+		{pattern: "/users/{id:int}/posts/{slug:slug}.json", name: "post"},
+		{pattern: "/tags/{name}", name: "tag"},
+		{pattern: "/files/*path", name: "file"},
+		{pattern: "/spans/{p:.+}/raw", name: "span"},
+	}
+	for _, r := range resources {
+		if err := router.SetResource(r.pattern, &namedResource{}, WithName(r.name)); err != nil {
+			t.Fatalf("SetResource failed pattern: %s err: %s", r.pattern, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		route   string
+		params  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Root URL can be built",
+			route: "index",
+			want:  "/",
+		},
+		{
+			name:   "URL with a parameter can be built",
+			route:  "user",
+			params: []string{"id", "10"},
+			want:   "/users/10",
+		},
+		{
+			name:   "URL with parameters and literals can be built",
+			route:  "post",
+			params: []string{"slug", "hello-world", "id", "10"},
+			want:   "/users/10/posts/hello-world.json",
+		},
+		{
+			name:   "Parameter value is escaped",
+			route:  "tag",
+			params: []string{"name", "go lang"},
+			want:   "/tags/go%20lang",
+		},
+		{
+			name:   "Catch-all value keeps slashes",
+			route:  "file",
+			params: []string{"path", "css/main file.css"},
+			want:   "/files/css/main%20file.css",
+		},
+		{
+			name:  "Catch-all value can be omitted",
+			route: "file",
+			want:  "/files",
+		},
+		{
+			name:    "Error because a value does not match the regular expression",
+			route:   "user",
+			params:  []string{"id", "ten"},
+			wantErr: true,
+		},
+		{
+			name:    "Error because a value does not match only partially",
+			route:   "post",
+			params:  []string{"id", "10", "slug", "hello--world"},
+			wantErr: true,
+		},
+		{
+			name:    "Error because a parameter is missing",
+			route:   "user",
+			wantErr: true,
+		},
+		{
+			name:    "Error because a parameter contains a slash",
+			route:   "tag",
+			params:  []string{"name", "go/lang"},
+			wantErr: true,
+		},
+		{
+			name:   "Parameter value matching slashes keeps them",
+			route:  "span",
+			params: []string{"p", "a/b c"},
+			want:   "/spans/a/b%20c/raw",
+		},
+		{
+			name:    "Error because params are not pairs",
+			route:   "user",
+			params:  []string{"id"},
+			wantErr: true,
+		},
+		{
+			name:    "Error because the route does not exist",
+			route:   "unknown",
+			wantErr: true,
+		},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			u, err := router.URL(td.route, td.params...)
+			if (err != nil) != td.wantErr {
+				t.Errorf("URL failed err: %s", err)
+			}
+
+			if u != td.want {
+				t.Errorf("URL result: %s, expected: %s", u, td.want)
+			}
+		})
+	}
+}
+
+func TestSetResourceDuplicateName(t *testing.T) {
+	router := NewRouter()
+	if err := router.SetResource("/users", &namedResource{}, WithName("users")); err != nil {
+		t.Fatalf("SetResource failed err: %s", err)
+	}
+
+	if err := router.SetResource("/members", &namedResource{}, WithName("users")); err == nil {
+		t.Error("SetResource must return an error for a name already used")
+	}
+}